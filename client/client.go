@@ -9,8 +9,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/anacrolix/torrent"
@@ -32,6 +35,12 @@ type Client struct {
 
 // NewClient initializes a new torrent client.
 func NewClient(dataDir string, torrentPort int, hostPort int) (Client, error) {
+	return NewClientWithProxy(dataDir, torrentPort, hostPort, nil)
+}
+
+// NewClientWithProxy initializes a new torrent client whose announces to HTTP trackers are sent through proxy (HTTP or SOCKS5).
+// UDP trackers, DHT and peers are still connected directly.
+func NewClientWithProxy(dataDir string, torrentPort int, hostPort int, proxy *url.URL) (Client, error) {
 	var client Client
 
 	// Initialize Config
@@ -41,6 +50,9 @@ func NewClient(dataDir string, torrentPort int, hostPort int) (Client, error) {
 	clientConfig.NoUpload = true
 	clientConfig.Seed = false
 	clientConfig.Debug = false
+	if proxy != nil {
+		clientConfig.HTTPProxy = http.ProxyURL(proxy)
+	}
 	client.ClientConfig = clientConfig
 
 	// Create Client
@@ -55,10 +67,17 @@ func NewClient(dataDir string, torrentPort int, hostPort int) (Client, error) {
 }
 
 // SetSource sets the source (magnet uri) which the client is based on.
+// Sources of local .torrent files (file:// URLs) are added from the files, so that their info is known at once.
 // * must be called before `Client.Start()`
 func (client *Client) SetSource(source models.Source) (*Client, error) {
 	client.Source = source
-	t, err := client.Client.AddMagnet(source.Magnet)
+	var t *torrent.Torrent
+	var err error
+	if strings.HasPrefix(source.URL, "file://") && strings.HasSuffix(source.URL, ".torrent") {
+		t, err = client.Client.AddTorrentFromFile(filepath.FromSlash(strings.TrimPrefix(source.URL, "file://")))
+	} else {
+		t, err = client.Client.AddMagnet(source.Magnet)
+	}
 	if err == nil {
 		t.SetDisplayName(source.Title)
 		client.Torrent = t
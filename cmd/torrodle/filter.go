@@ -0,0 +1,157 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"gopkg.in/AlecAivazis/survey.v1"
+
+	"github.com/tnychn/torrodle"
+)
+
+var (
+	minSizeFlag     = flag.String("min-size", "", "minimum file size (e.g. 700MB)")
+	maxSizeFlag     = flag.String("max-size", "", "maximum file size (e.g. 4GB)")
+	minSeedersFlag  = flag.Int("min-seeders", 0, "minimum amount of seeders")
+	includeFlag     = flag.String("include", "", "comma-separated keywords which must all appear in the title")
+	excludeFlag     = flag.String("exclude", "", "comma-separated keywords which must not appear in the title")
+	regexpFlag      = flag.String("regexp", "", "regular expression which the title must match")
+	resolutionsFlag = flag.String("resolutions", "", "comma-separated allowed resolutions (e.g. 1080p,720p)")
+	noCamFlag       = flag.Bool("no-cam", false, "exclude releases recorded in cinemas (CAM, TS, TC, SCR)")
+	maxAgeFlag      = flag.String("max-age", "", "maximum age of the upload date (e.g. 30d, 12h)")
+)
+
+var filterFlags = []string{"min-size", "max-size", "min-seeders", "include", "exclude", "regexp", "resolutions", "no-cam", "max-age"}
+
+// hasFilterFlags reports whether any of the filter flags is given in the command-line.
+func hasFilterFlags() bool {
+	given := false
+	flag.Visit(func(f *flag.Flag) {
+		for _, name := range filterFlags {
+			if f.Name == name {
+				given = true
+			}
+		}
+	})
+	return given
+}
+
+// filterFromFlags builds a torrodle.Filter from the filter flags.
+func filterFromFlags() (torrodle.Filter, error) {
+	var err error
+	filter := torrodle.Filter{
+		MinSeeders:  *minSeedersFlag,
+		Include:     splitList(*includeFlag),
+		Exclude:     splitList(*excludeFlag),
+		TitleRegexp: *regexpFlag,
+		Resolutions: splitList(*resolutionsFlag),
+		ExcludeCam:  *noCamFlag,
+	}
+	if filter.MinSize, err = parseSize(*minSizeFlag); err != nil {
+		return filter, fmt.Errorf("invalid -min-size: %v", err)
+	}
+	if filter.MaxSize, err = parseSize(*maxSizeFlag); err != nil {
+		return filter, fmt.Errorf("invalid -max-size: %v", err)
+	}
+	if filter.MaxAge, err = parseAge(*maxAgeFlag); err != nil {
+		return filter, fmt.Errorf("invalid -max-age: %v", err)
+	}
+	return filter, nil
+}
+
+// pickFilter prompts for the criteria of filtering the results.
+func pickFilter() torrodle.Filter {
+	var filter torrodle.Filter
+	need := false
+	_ = survey.AskOne(&survey.Confirm{Message: "Filter results?"}, &need, nil)
+	if !need {
+		return filter
+	}
+
+	seeders := ""
+	_ = survey.AskOne(&survey.Input{Message: "Minimum seeders:", Default: "0"}, &seeders, func(val interface{}) error {
+		if _, err := strconv.Atoi(val.(string)); err != nil {
+			return fmt.Errorf("input must be numbers")
+		}
+		return nil
+	})
+	filter.MinSeeders, _ = strconv.Atoi(seeders)
+
+	validateSize := func(val interface{}) error {
+		_, err := parseSize(val.(string))
+		return err
+	}
+	size := ""
+	_ = survey.AskOne(&survey.Input{Message: "Minimum size (e.g. 700MB):"}, &size, validateSize)
+	filter.MinSize, _ = parseSize(size)
+	size = ""
+	_ = survey.AskOne(&survey.Input{Message: "Maximum size (e.g. 4GB):"}, &size, validateSize)
+	filter.MaxSize, _ = parseSize(size)
+
+	keywords := ""
+	_ = survey.AskOne(&survey.Input{Message: "Include keywords (comma-separated):"}, &keywords, nil)
+	filter.Include = splitList(keywords)
+	keywords = ""
+	_ = survey.AskOne(&survey.Input{Message: "Exclude keywords (comma-separated):"}, &keywords, nil)
+	filter.Exclude = splitList(keywords)
+	_ = survey.AskOne(&survey.Input{Message: "Title regular expression:"}, &filter.TitleRegexp, func(val interface{}) error {
+		_, err := regexp.Compile(val.(string))
+		return err
+	})
+
+	prompt := &survey.MultiSelect{
+		Message: "Allowed resolutions (none for any):",
+		Options: []string{"2160p", "1080p", "720p", "576p", "480p"},
+	}
+	_ = survey.AskOne(prompt, &filter.Resolutions, nil)
+
+	_ = survey.AskOne(&survey.Confirm{Message: "Exclude CAM/TS releases?", Default: true}, &filter.ExcludeCam, nil)
+
+	age := ""
+	_ = survey.AskOne(&survey.Input{Message: "Maximum age (e.g. 30d):"}, &age, func(val interface{}) error {
+		_, err := parseAge(val.(string))
+		return err
+	})
+	filter.MaxAge, _ = parseAge(age)
+	return filter
+}
+
+// splitList splits a comma-separated list and drops the empty items.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// parseSize parses a human readable size such as "700MB" into bytes, an empty string is zero.
+func parseSize(s string) (int64, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	size, err := humanize.ParseBytes(s)
+	return int64(size), err
+}
+
+// parseAge parses a duration such as "12h" or "30d" (days), an empty string is zero.
+func parseAge(s string) (time.Duration, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return 0, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %v", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
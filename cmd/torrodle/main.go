@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"net/url"
 	"os"
 	"os/signal"
 	"os/user"
@@ -9,6 +12,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 	"unicode/utf8"
 
 	"github.com/dustin/go-humanize"
@@ -22,7 +26,17 @@ import (
 	"github.com/tnychn/torrodle/client"
 	"github.com/tnychn/torrodle/config"
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/parser"
 	"github.com/tnychn/torrodle/player"
+	"github.com/tnychn/torrodle/providers/local"
+	"github.com/tnychn/torrodle/providers/nyaa"
+	"github.com/tnychn/torrodle/providers/plugin"
+	"github.com/tnychn/torrodle/providers/rss"
+	"github.com/tnychn/torrodle/providers/scraper"
+	"github.com/tnychn/torrodle/providers/torznab"
+	"github.com/tnychn/torrodle/registry"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/rules"
 )
 
 const version = "1.0.4"
@@ -31,10 +45,14 @@ var u, _ = user.Current()
 var home = u.HomeDir
 var configFile = filepath.Join(home, ".torrodle.json")
 var configurations config.TorrodleConfig
+var scoringRules *rules.Engine
 
 var dataDir string
 var subtitlesDir string
 
+var sortFlag = flag.String("sort", "", `sort keys with optional order (e.g. "seeders desc, size asc")`)
+var noCacheFlag = flag.Bool("no-cache", false, "do not use (nor store) the cached search results")
+
 func errorPrint(arg ...interface{}) {
 	c := color.New(color.FgHiRed).Add(color.Bold)
 	_, _ = c.Print("✘ ")
@@ -57,7 +75,22 @@ func pickCategory() string {
 	return category
 }
 
-func pickProviders(options []string) []interface{} {
+func pickProviders(category torrodle.Category) []models.ProviderInterface {
+	// check for availibility of the category for each enabled provider
+	var options []string
+	byOption := make(map[string]models.ProviderInterface)
+	for _, provider := range registry.Enabled() {
+		if caturl, _ := torrodle.GetCategoryURL(category, provider.GetCategories()); caturl == "" {
+			continue
+		}
+		option := provider.GetName()
+		if provider.IsNSFW() {
+			option += " (NSFW)"
+		}
+		options = append(options, option)
+		byOption[option] = provider
+	}
+
 	var chosen []string
 	prompt := &survey.MultiSelect{
 		Message: "Choose providers:",
@@ -65,13 +98,9 @@ func pickProviders(options []string) []interface{} {
 	}
 	_ = survey.AskOne(prompt, &chosen, nil)
 
-	var providers []interface{}
+	var providers []models.ProviderInterface
 	for _, choice := range chosen {
-		for _, provider := range torrodle.AllProviders {
-			if provider.GetName() == choice {
-				providers = append(providers, provider)
-			}
-		}
+		providers = append(providers, byOption[choice])
 	}
 	return providers
 }
@@ -88,7 +117,7 @@ func pickSortBy() string {
 	prompt := &survey.Select{
 		Message: "Sort by:",
 		Default: "default",
-		Options: []string{"default", "seeders", "leechers", "size"},
+		Options: []string{"default", "seeders", "leechers", "size", "ratio", "date", "resolution", "relevance", "score"},
 	}
 	_ = survey.AskOne(prompt, &sortBy, nil)
 	return sortBy
@@ -112,13 +141,14 @@ func chooseResults(results []models.Source) string {
 	// Create table
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetAutoWrapText(false)
-	table.SetHeader([]string{"#", "Name", "S", "L", "Size"})
+	table.SetHeader([]string{"#", "Name", "S", "L", "Size", "Score"})
 	table.SetHeaderColor(
 		tablewriter.Colors{tablewriter.BgHiYellowColor, tablewriter.FgBlackColor},
 		tablewriter.Colors{tablewriter.Bold},
 		tablewriter.Colors{tablewriter.BgHiGreenColor, tablewriter.FgBlackColor},
 		tablewriter.Colors{tablewriter.BgHiRedColor, tablewriter.FgBlackColor},
 		tablewriter.Colors{tablewriter.BgHiCyanColor, tablewriter.FgBlackColor},
+		tablewriter.Colors{tablewriter.BgHiMagentaColor, tablewriter.FgBlackColor},
 	)
 	table.SetColumnColor(
 		tablewriter.Colors{tablewriter.FgHiYellowColor},
@@ -126,6 +156,7 @@ func chooseResults(results []models.Source) string {
 		tablewriter.Colors{tablewriter.FgHiGreenColor},
 		tablewriter.Colors{tablewriter.FgHiRedColor},
 		tablewriter.Colors{tablewriter.FgHiCyanColor},
+		tablewriter.Colors{tablewriter.FgHiMagentaColor},
 	)
 	for i, result := range results {
 		title := strings.TrimSpace(result.Title)
@@ -139,7 +170,7 @@ func chooseResults(results []models.Source) string {
 				title = string([]rune(title)[:22]) + "..."
 			}
 		}
-		table.Append([]string{strconv.Itoa(i + 1), title, strconv.Itoa(result.Seeders), strconv.Itoa(result.Leechers), humanize.Bytes(uint64(result.FileSize))})
+		table.Append([]string{strconv.Itoa(i + 1), title, strconv.Itoa(result.Seeders), strconv.Itoa(result.Leechers), humanize.Bytes(uint64(result.FileSize)), strconv.Itoa(result.Score)})
 	}
 	table.Render()
 
@@ -161,6 +192,21 @@ func chooseResults(results []models.Source) string {
 	return choice
 }
 
+// formatQuality joins the quality tags of a release, e.g. "1080p BluRay x264 HDR".
+func formatQuality(release parser.Release) string {
+	var tags []string
+	for _, tag := range []string{release.Resolution, release.Source, release.Codec, release.Audio} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if release.HDR {
+		tags = append(tags, "HDR")
+	}
+	tags = append(tags, release.Languages...)
+	return strings.Join(tags, " ")
+}
+
 func pickLangs() []string {
 	languagesMap := map[string]string{
 		"English":               "eng",
@@ -313,7 +359,11 @@ func startClient(player *player.Player, source models.Source, subtitlePath strin
 	// Play the video
 	infoPrint("Streaming torrent...")
 	// create client
-	c, err := client.NewClient(dataDir, configurations.TorrentPort, configurations.HostPort)
+	var proxy *url.URL
+	if configurations.ProxyTorrent && configurations.Proxy != "" {
+		proxy, _ = request.ParseProxy(configurations.Proxy) // validated when the config is loaded
+	}
+	c, err := client.NewClientWithProxy(dataDir, configurations.TorrentPort, configurations.HostPort, proxy)
 	if err != nil {
 		errorPrint(err)
 		os.Exit(1)
@@ -361,6 +411,14 @@ func startClient(player *player.Player, source models.Source, subtitlePath strin
 	}
 }
 
+// expandHome expands the user home directory of a path in the configurations file.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
 func init() {
 	var err error
 
@@ -378,6 +436,132 @@ func init() {
 		os.Exit(1)
 	}
 
+	scoringRules, err = rules.New(configurations.Rules)
+	if err != nil {
+		fmt.Println("Error loading config:", err)
+		os.Exit(1)
+	}
+
+	if configurations.CacheSize > 0 {
+		if err := request.SetCache(request.DefaultCacheDir(), int64(configurations.CacheSize)*1024*1024); err != nil {
+			logrus.Warningln("Cache disabled:", err)
+		}
+	}
+	if configurations.RetryAttempts > 0 {
+		policy := request.DefaultRetryPolicy
+		policy.MaxAttempts = configurations.RetryAttempts
+		request.SetRetryPolicy(policy)
+	}
+
+	for _, c := range configurations.Torznab {
+		if err := c.Validate(); err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+		if err := registry.Register(torznab.New(c)); err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+	}
+	for _, c := range configurations.RSS {
+		if err := c.Validate(); err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+		if err := registry.Register(rss.New(c)); err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+	}
+
+	var providers []models.ProviderInterface
+	if dir := expandHome(configurations.ProvidersDir); dir != "" {
+		scrapers, err := scraper.LoadDir(dir)
+		if err != nil {
+			fmt.Println("Error loading providers:", err)
+			os.Exit(1)
+		}
+		providers = append(providers, scrapers...)
+	}
+	if dir := expandHome(configurations.PluginsDir); dir != "" {
+		timeout := time.Duration(configurations.PluginTimeout) * time.Second
+		plugins, err := plugin.LoadDir(dir, timeout)
+		if err != nil {
+			fmt.Println("Error loading plugins:", err)
+			os.Exit(1)
+		}
+		providers = append(providers, plugins...)
+	}
+	if dir := expandHome(configurations.TorrentsDir); dir != "" {
+		providers = append(providers, local.New(dir))
+	}
+	for _, provider := range providers {
+		if err := registry.Register(provider); err != nil {
+			fmt.Println("Error loading providers:", err)
+			os.Exit(1)
+		}
+	}
+
+	if configurations.Nyaa != (nyaa.Options{}) {
+		_ = registry.Unregister(nyaa.Name)
+		registry.MustRegister(nyaa.NewWithOptions(configurations.Nyaa))
+	}
+
+	for name, sites := range configurations.Mirrors {
+		provider, ok := registry.Lookup(name)
+		if !ok {
+			fmt.Printf("Error loading config: %v: %v\n", registry.ErrNotFound, name)
+			os.Exit(1)
+		}
+		provider.SetMirrors(sites)
+	}
+	if configurations.Proxy != "" {
+		proxy, err := request.ParseProxy(configurations.Proxy)
+		if err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+		request.SetDefaultProxy(proxy)
+	}
+	for name, p := range configurations.Proxies {
+		provider, ok := registry.Lookup(name)
+		if !ok {
+			fmt.Printf("Error loading config: %v: %v\n", registry.ErrNotFound, name)
+			os.Exit(1)
+		}
+		var proxy *url.URL
+		if p != "" {
+			if proxy, err = request.ParseProxy(p); err != nil {
+				fmt.Println("Error loading config:", err)
+				os.Exit(1)
+			}
+		}
+		for _, site := range provider.GetMirrors() {
+			if u, err := url.Parse(site); err == nil && u.Host != "" {
+				request.SetProxy(u.Host, proxy)
+			}
+		}
+	}
+	for name, limit := range configurations.Limits {
+		provider, ok := registry.Lookup(name)
+		if !ok {
+			fmt.Printf("Error loading config: %v: %v\n", registry.ErrNotFound, name)
+			os.Exit(1)
+		}
+		for _, site := range provider.GetMirrors() {
+			if u, err := url.Parse(site); err == nil && u.Host != "" {
+				request.SetLimit(u.Host, limit)
+			}
+		}
+	}
+
+	for _, name := range configurations.DisabledProviders {
+		if err := registry.Disable(name); err != nil {
+			fmt.Println("Error loading config:", err)
+			os.Exit(1)
+		}
+	}
+
 	dataDir = configurations.DataDir
 	if dataDir == "" {
 		dataDir = filepath.Join(os.TempDir(), "torrodle")
@@ -408,6 +592,25 @@ func init() {
 }
 
 func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [magnet]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *noCacheFlag {
+		_ = request.SetCache("", 0)
+	}
+	filter, err := filterFromFlags()
+	if err != nil {
+		errorPrint(err)
+		os.Exit(2)
+	}
+	spec, err := torrodle.ParseSortSpec(*sortFlag)
+	if err != nil {
+		errorPrint(err)
+		os.Exit(2)
+	}
+
 	name := color.HiYellowString("[torrodle v%s]", version)
 	banner :=
 		`
@@ -430,12 +633,12 @@ func main() {
 	logrus.Debug(configurations)
 
 	// Stream torrent from magnet provided in command-line
-	if len(os.Args) > 1 {
+	if flag.NArg() > 0 {
 		// make source
 		source := models.Source{
 			From:   "User Provided",
 			Title:  "Unknown",
-			Magnet: os.Args[1],
+			Magnet: flag.Arg(0),
 		}
 		// player
 		playerChoice := pickPlayer()
@@ -460,14 +663,7 @@ func main() {
 		return
 	}
 	cat := torrodle.Category(strings.ToUpper(category))
-	var options []string
-	// check for availibility of each category for each provider
-	for _, provider := range torrodle.AllProviders {
-		if torrodle.GetCategoryURL(cat, provider.GetCategories()) != "" {
-			options = append(options, provider.GetName())
-		}
-	}
-	providers := pickProviders(options)
+	providers := pickProviders(cat)
 	if len(providers) == 0 {
 		errorPrint("Operation aborted")
 		return
@@ -478,16 +674,57 @@ func main() {
 		errorPrint("Operation aborted")
 		return
 	}
-	sortBy := pickSortBy()
-	if sortBy == "" {
-		errorPrint("Operation aborted")
-		return
+	if len(spec) == 0 {
+		sortBy := pickSortBy()
+		if sortBy == "" {
+			errorPrint("Operation aborted")
+			return
+		}
+		spec = torrodle.SortSpec{{By: torrodle.SortBy(strings.ToLower(sortBy)), Desc: true}}
+	}
+	if !hasFilterFlags() {
+		filter = pickFilter()
 	}
-	sb := torrodle.SortBy(strings.ToLower(sortBy))
+	filter.MinRelevance = configurations.MinRelevance
 
 	// Call torrodle API to search for torrents
 	limit := configurations.ResultsLimit
-	results := torrodle.ListResults(providers, query, limit, cat, sb)
+	opts := torrodle.SearchOptions{
+		Providers: providers,
+		Query:     query,
+		Count:     limit,
+		Category:  cat,
+		Sort:      spec,
+		Filter:    filter,
+		Rules:     scoringRules,
+	}
+	if logrus.GetLevel() <= logrus.WarnLevel {
+		p := newProgress()
+		defer p.Stop()
+		opts.Progress = p.Update
+	}
+	results, err := torrodle.Search(context.Background(), opts)
+	if err != nil {
+		errs, ok := err.(torrodle.ProviderErrors)
+		if !ok {
+			errorPrint(err)
+			os.Exit(1)
+		}
+		for _, e := range errs {
+			switch request.Cause(e.Err) {
+			case torrodle.ErrNoResults:
+				logrus.Warningln(e)
+			case torrodle.ErrBlocked:
+				errorPrint(e)
+				errorPrint("  the site may be blocked in your network, try a mirror (Mirrors) or a proxy (Proxy) in the config")
+			case torrodle.ErrLayoutChanged:
+				errorPrint(e)
+				errorPrint("  the provider may be broken, please report it")
+			default:
+				errorPrint(e)
+			}
+		}
+	}
 	if len(results) == 0 {
 		errorPrint("No torrents found")
 		return
@@ -508,7 +745,7 @@ func main() {
 	_, _ = boldYellow.Print("Title: ")
 	fmt.Println(source.Title)
 	_, _ = boldYellow.Print("From: ")
-	fmt.Println(source.From)
+	fmt.Println(strings.Join(source.Providers, ", "))
 	_, _ = boldYellow.Print("URL: ")
 	fmt.Println(source.URL)
 	_, _ = boldYellow.Print("Seeders: ")
@@ -517,6 +754,40 @@ func main() {
 	color.Red(strconv.Itoa(source.Leechers))
 	_, _ = boldYellow.Print("FileSize: ")
 	color.Cyan(strconv.Itoa(int(source.FileSize)))
+	if source.Category != "" {
+		_, _ = boldYellow.Print("Category: ")
+		fmt.Println(source.Category)
+	}
+	if source.Uploader != "" {
+		_, _ = boldYellow.Print("Uploader: ")
+		fmt.Println(source.Uploader)
+	}
+	if source.Trusted {
+		_, _ = boldYellow.Print("Trusted: ")
+		color.Green("yes")
+	}
+	if source.Remake {
+		_, _ = boldYellow.Print("Remake: ")
+		color.Red("yes")
+	}
+	if !source.UploadDate.IsZero() {
+		_, _ = boldYellow.Print("Uploaded: ")
+		fmt.Println(source.UploadDate.Format("2006-01-02"))
+	}
+	if quality := formatQuality(source.Release); quality != "" {
+		_, _ = boldYellow.Print("Quality: ")
+		fmt.Println(quality)
+	}
+	if source.IMDB != "" {
+		_, _ = boldYellow.Print("IMDb: ")
+		fmt.Println(source.IMDB)
+	}
+	if len(source.Files) > 0 {
+		_, _ = boldYellow.Print("Files: ")
+		fmt.Println(len(source.Files))
+	}
+	_, _ = boldYellow.Print("InfoHash: ")
+	fmt.Println(source.InfoHash)
 	_, _ = boldYellow.Print("Magnet: ")
 	fmt.Println(source.Magnet)
 
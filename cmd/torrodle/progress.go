@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+
+	"github.com/tnychn/torrodle"
+)
+
+// progress draws a spinner listing the providers which are still being waited for.
+type progress struct {
+	spinner *spinner.Spinner
+	pending []string
+}
+
+func newProgress() *progress {
+	s := spinner.New(spinner.CharSets[33], 100*time.Millisecond)
+	_ = s.Color("fgBlue")
+	return &progress{spinner: s}
+}
+
+// Update handles a search event, it is used as torrodle.SearchOptions.Progress.
+func (p *progress) Update(event torrodle.SearchEvent) {
+	switch event.Type {
+	case torrodle.EventStarted:
+		p.pending = append(p.pending, event.Provider)
+	case torrodle.EventDone:
+		for i, name := range p.pending {
+			if name == event.Provider {
+				p.pending = append(p.pending[:i], p.pending[i+1:]...)
+				break
+			}
+		}
+	default:
+		return
+	}
+	if len(p.pending) == 0 {
+		p.spinner.Stop()
+		return
+	}
+	c := color.New(color.FgYellow, color.Bold)
+	suffix := c.Sprint(" Waiting for ") + color.GreenString(strings.Join(p.pending, ", ")) + c.Sprint(" ...")
+	// the spinner goroutine reads the suffix under its own lock
+	p.spinner.Lock()
+	p.spinner.Suffix = suffix
+	p.spinner.Unlock()
+	p.spinner.Start()
+}
+
+// Stop stops the spinner if it is still running.
+func (p *progress) Stop() {
+	p.spinner.Stop()
+}
@@ -4,29 +4,71 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+
+	"github.com/tnychn/torrodle/providers/nyaa"
+	"github.com/tnychn/torrodle/providers/rss"
+	"github.com/tnychn/torrodle/providers/torznab"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/rules"
 )
 
 type TorrodleConfig struct {
-	DataDir      string `json:"DataDir"`
-	ResultsLimit int    `json:"ResultsLimit"`
-	TorrentPort  int    `json:"TorrentPort"`
-	HostPort     int    `json:"HostPort"`
-	Debug        bool   `json:"Debug"`
+	DataDir      string  `json:"DataDir"`
+	ResultsLimit int     `json:"ResultsLimit"`
+	MinRelevance float64 `json:"MinRelevance"`
+	TorrentPort  int     `json:"TorrentPort"`
+	HostPort     int     `json:"HostPort"`
+	Debug        bool    `json:"Debug"`
+
+	DisabledProviders []string                 `json:"DisabledProviders"`
+	Mirrors           map[string][]string      `json:"Mirrors"`      // provider name -> sites, replacing the built-in mirrors
+	Limits            map[string]request.Limit `json:"Limits"`       // provider name -> limit of the requests sent to each of its sites
+	Proxy             string                   `json:"Proxy"`        // URL of the proxy of all the requests, e.g. "socks5://127.0.0.1:9050"
+	Proxies           map[string]string        `json:"Proxies"`      // provider name -> proxy of its sites, "" to send its requests directly
+	ProxyTorrent      bool                     `json:"ProxyTorrent"` // whether the announces to HTTP trackers use Proxy too
+	Rules             []rules.Rule             `json:"Rules"`
+
+	Nyaa          nyaa.Options     `json:"Nyaa"` // options of the Nyaa provider
+	Torznab       []torznab.Config `json:"Torznab"`
+	RSS           []rss.Config     `json:"RSS"`
+	ProvidersDir  string           `json:"ProvidersDir"`
+	PluginsDir    string           `json:"PluginsDir"`
+	PluginTimeout int              `json:"PluginTimeout"` // in seconds
+	TorrentsDir   string           `json:"TorrentsDir"`
+	RetryAttempts int              `json:"RetryAttempts"` // attempts of a request which failed temporarily
+	CacheSize     int              `json:"CacheSize"`     // in megabytes, 0 disables the cache
 }
 
 func (t TorrodleConfig) String() string {
 	return fmt.Sprintf(
-		`TorrentDir: %v | ResultsLimit: %d | TorrentPort: %d | HostPort: %d | Debug: %v`,
-		t.DataDir, t.ResultsLimit, t.TorrentPort, t.HostPort, t.Debug,
+		`TorrentDir: %v | ResultsLimit: %d | MinRelevance: %v | TorrentPort: %d | HostPort: %d | Debug: %v | DisabledProviders: %v | Mirrors: %d | Limits: %d | Proxy: %v | Proxies: %d | ProxyTorrent: %v | Rules: %d | Nyaa: %+v | Torznab: %d | RSS: %d | ProvidersDir: %v | PluginsDir: %v | PluginTimeout: %d | TorrentsDir: %v | RetryAttempts: %d | CacheSize: %d`,
+		t.DataDir, t.ResultsLimit, t.MinRelevance, t.TorrentPort, t.HostPort, t.Debug, t.DisabledProviders, len(t.Mirrors), len(t.Limits), t.Proxy, len(t.Proxies), t.ProxyTorrent, len(t.Rules), t.Nyaa, len(t.Torznab), len(t.RSS), t.ProvidersDir, t.PluginsDir, t.PluginTimeout, t.TorrentsDir, t.RetryAttempts, t.CacheSize,
 	)
 }
 
 func InitConfig(path string) error {
 	config := TorrodleConfig{
-		DataDir:      "",
-		ResultsLimit: 100,
-		TorrentPort:  9999,
-		HostPort:     8080,
+		DataDir:           "",
+		ResultsLimit:      100,
+		MinRelevance:      0.5,
+		TorrentPort:       9999,
+		HostPort:          8080,
+		DisabledProviders: []string{},
+		Mirrors:           map[string][]string{},
+		Limits:            map[string]request.Limit{},
+		Proxy:             "",
+		Proxies:           map[string]string{},
+		ProxyTorrent:      false,
+		Rules:             []rules.Rule{},
+		Nyaa:              nyaa.Options{},
+		Torznab:           []torznab.Config{},
+		RSS:               []rss.Config{},
+		ProvidersDir:      "~/.torrodle/providers",
+		PluginsDir:        "~/.torrodle/plugins",
+		PluginTimeout:     30,
+		TorrentsDir:       "",
+		RetryAttempts:     3,
+		CacheSize:         100,
 	}
 	data, _ := json.MarshalIndent(config, "", "\t")
 	err := ioutil.WriteFile(path, data, 0644)
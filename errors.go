@@ -0,0 +1,50 @@
+package torrodle
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tnychn/torrodle/request"
+)
+
+var (
+	ErrInvalidCategory = errors.New("invalid category")
+	ErrInvalidSortBy   = errors.New("invalid sortBy")
+	ErrInvalidProvider = errors.New("invalid interface type in 'providers': only 'string' and 'models.ProviderInterface' are accepted")
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrNoResults       = errors.New("no torrents found")
+	ErrTimeout         = errors.New("timed out")
+
+	// A provider failed with an error whose cause (see request.Cause) is one of these if it was served a challenge or block page,
+	// or if its results could not be found in the page (e.g. after the site changed its layout).
+	ErrBlocked       = request.ErrBlocked
+	ErrLayoutChanged = request.ErrLayoutChanged
+)
+
+// ProviderError records the failure of a single provider during a search.
+type ProviderError struct {
+	Provider string // name of the provider that failed
+	Err      error  // underlying error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Provider, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderErrors is a collection of errors of every provider that failed during a search.
+// It is returned along with the partial results collected from the remaining providers.
+type ProviderErrors []*ProviderError
+
+func (errs ProviderErrors) Error() string {
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,84 @@
+package torrodle
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tnychn/torrodle/models"
+)
+
+// Filter narrows down the results of a search.
+// Zero values disable the corresponding criteria.
+type Filter struct {
+	MinSize      int64         // minimum file size in bytes
+	MaxSize      int64         // maximum file size in bytes
+	MinSeeders   int           // minimum amount of seeders
+	Include      []string      // keywords which must all appear in the title (case-insensitive)
+	Exclude      []string      // keywords which must not appear in the title (case-insensitive)
+	TitleRegexp  string        // regular expression which the title must match
+	Resolutions  []string      // allowed resolutions of the parsed release (e.g. "1080p")
+	ExcludeCam   bool          // drop releases recorded in cinemas (CAM, TS, TC and SCR)
+	MaxAge       time.Duration // maximum age of the upload date, results without an upload date are kept
+	MinRelevance float64       // minimum relevance of the title to the query (see Relevance)
+}
+
+// FilterResults returns the results which satisfy all the criteria of filter.
+// An error is returned if filter.TitleRegexp is not a valid regular expression.
+func FilterResults(results []models.Source, filter Filter) ([]models.Source, error) {
+	var re *regexp.Regexp
+	if filter.TitleRegexp != "" {
+		var err error
+		if re, err = regexp.Compile(filter.TitleRegexp); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+
+	var filtered []models.Source
+	for _, result := range results {
+		title := strings.ToLower(result.Title)
+		switch {
+		case filter.MinSize > 0 && result.FileSize < filter.MinSize:
+		case filter.MaxSize > 0 && result.FileSize > filter.MaxSize:
+		case result.Seeders < filter.MinSeeders:
+		case !containsAll(title, filter.Include):
+		case containsAny(title, filter.Exclude):
+		case re != nil && !re.MatchString(result.Title):
+		case len(filter.Resolutions) > 0 && !containsFold(filter.Resolutions, result.Release.Resolution):
+		case filter.ExcludeCam && result.Release.IsCam():
+		case filter.MaxAge > 0 && !result.UploadDate.IsZero() && now.Sub(result.UploadDate) > filter.MaxAge:
+		case result.Relevance < filter.MinRelevance:
+		default:
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
+func containsAll(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if !strings.Contains(s, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
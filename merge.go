@@ -0,0 +1,83 @@
+package torrodle
+
+import (
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/utils"
+)
+
+// MergeResults merges the results which refer to the same torrent (same info hash).
+// InfoHash is filled in from the magnet uri if the provider did not set it.
+// The merged result keeps the position and the fields of its first occurrence, the maximum amount of
+// seeders and leechers, the maximum relevance and score, whether any of them is trusted, the missing fields of the others, and records every provider which listed it in Providers.
+// Results without an info hash are never merged.
+func MergeResults(results []models.Source) []models.Source {
+	var merged []models.Source
+	indexes := make(map[string]int) // info hash -> index in merged
+	for _, result := range results {
+		if len(result.Providers) == 0 {
+			result.Providers = []string{result.From}
+		} else {
+			result.Providers = append([]string(nil), result.Providers...)
+		}
+		if result.InfoHash == "" {
+			result.InfoHash = utils.InfoHashFromMagnet(result.Magnet)
+		}
+		hash := result.InfoHash
+		i, ok := indexes[hash]
+		if hash == "" || !ok {
+			if hash != "" {
+				indexes[hash] = len(merged)
+			}
+			merged = append(merged, result)
+			continue
+		}
+
+		m := &merged[i]
+		if result.Seeders > m.Seeders {
+			m.Seeders = result.Seeders
+		}
+		if result.Leechers > m.Leechers {
+			m.Leechers = result.Leechers
+		}
+		if result.Relevance > m.Relevance {
+			m.Relevance = result.Relevance
+		}
+		if result.Score > m.Score {
+			m.Score = result.Score
+		}
+		if m.FileSize == 0 {
+			m.FileSize = result.FileSize
+		}
+		if m.UploadDate.IsZero() {
+			m.UploadDate = result.UploadDate
+		}
+		if m.Category == "" {
+			m.Category = result.Category
+		}
+		if m.Uploader == "" {
+			m.Uploader = result.Uploader
+		}
+		if m.IMDB == "" {
+			m.IMDB = result.IMDB
+		}
+		if len(m.Files) == 0 {
+			m.Files = result.Files
+		}
+		m.Trusted = m.Trusted || result.Trusted
+		for _, provider := range result.Providers {
+			if !contains(m.Providers, provider) {
+				m.Providers = append(m.Providers, provider)
+			}
+		}
+	}
+	return merged
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
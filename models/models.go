@@ -4,30 +4,42 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/tnychn/torrodle/parser"
 	"github.com/tnychn/torrodle/utils"
 )
 
 // ProviderInterface is an interface that exposes all the methods a `Provider` struct type has.
 type ProviderInterface interface {
 	String() string
-	Search(string, int, CategoryURL) ([]Source, error) // search for torrents with a given (query, count, categoryURL) -> returns a slice of sources found
-	Query(string, CategoryURL, int, int, int, func(string, int, *[]Source, *sync.WaitGroup)) ([]Source, error)
+	Search(context.Context, string, int, CategoryURL) ([]Source, error) // search for torrents with a given (ctx, query, count, categoryURL) -> returns a slice of sources found
+	Query(context.Context, string, CategoryURL, int, int, int, Extractor) ([]Source, error)
 	GetName() string
 	GetSite() string
+	GetMirrors() []string
+	SetMirrors([]string)
+	WithMirrors(context.Context, func(site string) error) error
 	GetCategories() Categories
+	IsNSFW() bool
 }
 
 // Provider is a struct type that exposes fields for the `ProviderInterface`.
 type Provider struct {
 	Name       string
 	Site       string
+	Mirrors    []string // other sites of the provider with the same layout, tried in order when Site fails
 	Categories Categories
+	NSFW       bool // whether the provider is mostly for adult content
+
+	mutex  sync.Mutex
+	mirror int // index of the last site in GetMirrors which worked
 }
 
 func (provider *Provider) String() string {
@@ -35,7 +47,7 @@ func (provider *Provider) String() string {
 }
 
 // Search queries the provider and returns the results (sources).
-func (provider *Provider) Search(string, int, CategoryURL) ([]Source, error) {
+func (provider *Provider) Search(context.Context, string, int, CategoryURL) ([]Source, error) {
 	return []Source{}, nil
 }
 
@@ -46,16 +58,79 @@ func (provider *Provider) GetName() string {
 
 // GetSite returns the URL (site domain) of this provider.
 func (provider *Provider) GetSite() string {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
 	return provider.Site
 }
 
+// GetMirrors returns all the sites of this provider in order, starting with Site.
+func (provider *Provider) GetMirrors() []string {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	return provider.sites()
+}
+
+// SetMirrors replaces all the sites of this provider, the first one becomes Site.
+func (provider *Provider) SetMirrors(sites []string) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	if len(sites) == 0 {
+		return
+	}
+	provider.Site = sites[0]
+	provider.Mirrors = append([]string(nil), sites[1:]...)
+	provider.mirror = 0
+}
+
+// WithMirrors calls do with the site which worked last time, and with the next sites in turn while it fails.
+// The site which worked is remembered for the next calls. Returns the last error if every site failed.
+func (provider *Provider) WithMirrors(ctx context.Context, do func(site string) error) error {
+	provider.mutex.Lock()
+	sites := provider.sites()
+	start := provider.mirror
+	provider.mutex.Unlock()
+
+	var err error
+	for i := range sites {
+		index := (start + i) % len(sites)
+		if err = do(sites[index]); err == nil {
+			provider.mutex.Lock()
+			provider.mirror = index
+			provider.mutex.Unlock()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		if i < len(sites)-1 {
+			logrus.Warningf("%v: %v failed, trying the next mirror: %v\n", provider.Name, sites[index], err)
+		}
+	}
+	return err
+}
+
+// sites returns Site followed by Mirrors, the caller must hold the mutex.
+func (provider *Provider) sites() []string {
+	return append([]string{provider.Site}, provider.Mirrors...)
+}
+
 // GetCategories returns the categories of this provider.
 func (provider *Provider) GetCategories() Categories {
 	return provider.Categories
 }
 
+// Extractor extracts the sources from a single page of search results.
+type Extractor func(ctx context.Context, surl string, page int) ([]Source, error)
+
+// IsNSFW returns whether this provider is mostly for adult content.
+func (provider *Provider) IsNSFW() bool {
+	return provider.NSFW
+}
+
 // Query is a universal base function for querying webpages asynchronusly.
-func (provider *Provider) Query(query string, categoryURL CategoryURL, count int, perPage int, start int, extractor func(string, int, *[]Source, *sync.WaitGroup)) ([]Source, error) {
+// Each page is tried against the mirrors of the provider (see WithMirrors) until one succeeds.
+// Pages that failed are skipped, an error is only returned if no page succeeded.
+func (provider *Provider) Query(ctx context.Context, query string, categoryURL CategoryURL, count int, perPage int, start int, extractor Extractor) ([]Source, error) {
 	var results []Source
 	if count <= 0 {
 		return results, nil
@@ -70,15 +145,37 @@ func (provider *Provider) Query(query string, categoryURL CategoryURL, count int
 	logrus.Debugf("%v: pages=%d\n", provider.Name, pages)
 
 	// asynchronize
+	sources := make([][]Source, pages-start+1)
+	errs := make([]error, pages-start+1)
 	wg := sync.WaitGroup{}
 	for page := start; page <= pages; page++ {
 		surl := fmt.Sprintf(string(categoryURL), query, page)
 		wg.Add(1)
-		go extractor(provider.Site+surl, page, &results, &wg)
+		go func(i int, page int) {
+			defer wg.Done()
+			errs[i] = provider.WithMirrors(ctx, func(site string) error {
+				var err error
+				sources[i], err = extractor(ctx, site+surl, page)
+				return err
+			})
+			if errs[i] != nil {
+				logrus.Errorln(fmt.Sprintf("%v: [%d]", provider.Name, page), errs[i])
+			}
+		}(page-start, page)
 	}
 	wg.Wait()
 
 	// Ending up
+	var err error
+	for i := range sources {
+		results = append(results, sources[i]...)
+		if errs[i] != nil && err == nil {
+			err = errs[i]
+		}
+	}
+	if len(results) == 0 && err != nil {
+		return results, err
+	}
 	logrus.Infof("%v: Found %d results\n", provider.Name, len(results))
 	if len(results) < count {
 		count = len(results)
@@ -100,13 +197,31 @@ type Categories struct {
 
 // Source provides informational fields for a torrent source.
 type Source struct {
-	From     string
-	Title    string
-	URL      string
-	Seeders  int
-	Leechers int
-	FileSize int64
-	Magnet   string
+	From       string
+	Providers  []string // all the providers which listed this torrent (set after merging)
+	Title      string
+	URL        string
+	Seeders    int
+	Leechers   int
+	FileSize   int64
+	Magnet     string
+	InfoHash   string    // info hash as a lowercase hex string
+	UploadDate time.Time // zero if unknown
+	Category   string    // category given by the provider
+	Uploader   string
+	Trusted    bool           // uploaded by a trusted user of the provider
+	Remake     bool           // marked as a remake (re-encode or re-release) by the provider
+	IMDB       string         // IMDb id such as "tt0944947" (empty if unknown)
+	Files      []File         // files of the torrent (empty if unknown)
+	Release    parser.Release // metadata parsed from the title
+	Relevance  float64        // relevance of the title to the query, from 0 to 1
+	Score      int            // score given by the user-defined rules
+}
+
+// File is a file of a torrent.
+type File struct {
+	Path   string // path inside the torrent, separated by "/"
+	Length int64  // size in bytes
 }
 
 func (source Source) String() string {
@@ -0,0 +1,235 @@
+// Package parser extracts the metadata of a release from its name, e.g. "Movie.2019.1080p.BluRay.x264-GROUP".
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Release holds the metadata parsed from a release name.
+// Fields which cannot be found in the name are left empty.
+type Release struct {
+	Title      string   // title without any of the tags
+	Year       int      // year of release
+	Season     int      // season number of a TV show
+	Episode    int      // episode number of a TV show (zero for a whole season)
+	Resolution string   // e.g. "2160p", "1080p", "720p"
+	Source     string   // e.g. "BluRay", "WEB-DL", "HDTV", "CAM"
+	Codec      string   // e.g. "x264", "x265"
+	Audio      string   // e.g. "AAC", "DTS", "DD5.1"
+	HDR        bool     // whether the video is in HDR (including Dolby Vision)
+	Group      string   // release group
+	Languages  []string // e.g. "MULTI", "FRENCH", "KOREAN"
+}
+
+// tag maps a pattern found in a release name to its normalized value.
+type tag struct {
+	re    *regexp.Regexp
+	value string
+}
+
+func newTag(pattern string, value string) tag {
+	return tag{regexp.MustCompile(`(?i)\b(?:` + pattern + `)\b`), value}
+}
+
+var (
+	episodeRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\bS(\d{1,2}) ?E(\d{1,3})\b`),
+		regexp.MustCompile(`(?i)\b(\d{1,2})x(\d{2,3})\b`),
+	}
+	seasonRegexp       = regexp.MustCompile(`(?i)\b(?:S|Season ?)(\d{1,2})\b`)
+	animeEpisodeRegexp = regexp.MustCompile(`^\[[^\]]+\].*? - (\d{1,4})\b`)
+	yearRegexp         = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+	resolutionRegexp   = regexp.MustCompile(`(?i)\b(2160p|4k|uhd|1080[pi]|720p|576p|480p|360p)\b`)
+	hdrRegexp          = regexp.MustCompile(`(?i)\b(?:hdr(?:10)?\+?|dolby ?vision|dovi|dv)\b`)
+	groupRegexp        = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\[[^\]]*\])?$`)
+	animeGroupRegexp   = regexp.MustCompile(`^\[([^\]]+)\]`)
+	extensionRegexp    = regexp.MustCompile(`(?i)\.(?:mkv|mp4|avi|m4v|ts)$`)
+	separatorRegexp    = regexp.MustCompile(`[\s._]+`)
+
+	// tags are checked in order, the first match wins
+	sources = []tag{
+		newTag(`hd-?cam|cam-?rip|cam`, "CAM"),
+		newTag(`hd-?ts|telesync|pdvd|ts`, "TS"),
+		newTag(`hd-?tc|telecine|tc`, "TC"),
+		newTag(`dvd-?scr|screener|scr`, "SCR"),
+		newTag(`remux|bd-?remux`, "Remux"),
+		newTag(`blu-?ray|bd-?rip|br-?rip|bd`, "BluRay"),
+		newTag(`hd-?rip`, "HDRip"),
+		newTag(`web-?dl|webdl`, "WEB-DL"),
+		newTag(`web-?rip`, "WEBRip"),
+		newTag(`web`, "WEB"),
+		newTag(`hdtv|pdtv`, "HDTV"),
+		newTag(`dvd-?rip|dvd-?r|dvd`, "DVDRip"),
+	}
+	codecs = []tag{
+		newTag(`x265|h\.?265|hevc`, "x265"),
+		newTag(`x264|h\.?264|avc`, "x264"),
+		newTag(`xvid`, "XviD"),
+		newTag(`divx`, "DivX"),
+		newTag(`av1`, "AV1"),
+		newTag(`vp9`, "VP9"),
+	}
+	audios = []tag{
+		newTag(`truehd`, "TrueHD"),
+		newTag(`atmos`, "Atmos"),
+		newTag(`dts-?hd(?:[ .-]?ma)?`, "DTS-HD"),
+		newTag(`dts`, "DTS"),
+		newTag(`ddp ?5\.1|dd\+ ?5\.1|e-?ac-?3`, "DD+5.1"),
+		newTag(`dd ?5\.1|ac-?3`, "DD5.1"),
+		newTag(`aac(?: ?2\.0)?`, "AAC"),
+		newTag(`flac`, "FLAC"),
+		newTag(`opus`, "Opus"),
+		newTag(`mp3`, "MP3"),
+	}
+	languages = []tag{
+		newTag(`multi`, "MULTI"),
+		newTag(`dual(?:[ .-]?audio)?`, "DUAL"),
+		newTag(`english|eng`, "ENGLISH"),
+		newTag(`truefrench|french|vff|vostfr`, "FRENCH"),
+		newTag(`german|ger`, "GERMAN"),
+		newTag(`italian|ita`, "ITALIAN"),
+		newTag(`spanish|esp|latino`, "SPANISH"),
+		newTag(`russian|rus`, "RUSSIAN"),
+		newTag(`hindi`, "HINDI"),
+		newTag(`japanese|jap`, "JAPANESE"),
+		newTag(`korean|kor|korsub`, "KOREAN"),
+		newTag(`chinese|chs|cht`, "CHINESE"),
+	}
+)
+
+// Parse parses the name of a release.
+func Parse(name string) Release {
+	var release Release
+	name = extensionRegexp.ReplaceAllString(strings.TrimSpace(name), "")
+	// underscores are word characters for \b, treat them as spaces
+	normalized := strings.Replace(name, "_", " ", -1)
+	end := len(normalized) // the title ends before the first tag found
+
+	found := func(loc []int) {
+		if loc != nil && loc[0] < end && loc[0] > 0 {
+			end = loc[0]
+		}
+	}
+
+	// season and episode
+	for _, re := range episodeRegexps {
+		if m := re.FindStringSubmatchIndex(normalized); m != nil {
+			release.Season, _ = strconv.Atoi(normalized[m[2]:m[3]])
+			release.Episode, _ = strconv.Atoi(normalized[m[4]:m[5]])
+			found(m)
+			break
+		}
+	}
+	if release.Season == 0 {
+		if m := seasonRegexp.FindStringSubmatchIndex(normalized); m != nil {
+			release.Season, _ = strconv.Atoi(normalized[m[2]:m[3]])
+			found(m)
+		} else if m := animeEpisodeRegexp.FindStringSubmatchIndex(normalized); m != nil {
+			release.Episode, _ = strconv.Atoi(normalized[m[2]:m[3]])
+			found([]int{m[2] - 3})
+		}
+	}
+	// year: the last one, so that a year in the title itself is kept (e.g. "2001 A Space Odyssey 1968")
+	if ms := yearRegexp.FindAllStringSubmatchIndex(normalized, -1); ms != nil {
+		m := ms[len(ms)-1]
+		if m[0] > 0 {
+			release.Year, _ = strconv.Atoi(normalized[m[2]:m[3]])
+			found(m)
+		}
+	}
+	// resolution
+	if m := resolutionRegexp.FindStringSubmatchIndex(normalized); m != nil {
+		switch r := strings.ToLower(normalized[m[2]:m[3]]); r {
+		case "4k", "uhd":
+			release.Resolution = "2160p"
+		case "1080i":
+			release.Resolution = "1080p"
+		default:
+			release.Resolution = r
+		}
+		found(m)
+	}
+
+	// Other tags are only looked for after the ones above (if any),
+	// so that words of the title (e.g. "Charlotte's Web") are not taken as tags.
+	offset := 0
+	if end < len(normalized) {
+		offset = end
+	}
+	tail := normalized[offset:]
+	foundInTail := func(loc []int) {
+		if loc != nil {
+			found([]int{loc[0] + offset, loc[1] + offset})
+		}
+	}
+	release.Source = matchTag(sources, tail, foundInTail)
+	release.Codec = matchTag(codecs, tail, foundInTail)
+	release.Audio = matchTag(audios, tail, foundInTail)
+	if m := hdrRegexp.FindStringIndex(tail); m != nil {
+		release.HDR = true
+		foundInTail(m)
+	}
+	for _, t := range languages {
+		if m := t.re.FindStringIndex(tail); m != nil {
+			release.Languages = append(release.Languages, t.value)
+			foundInTail(m)
+		}
+	}
+	// release group
+	if m := animeGroupRegexp.FindStringSubmatch(normalized); m != nil {
+		release.Group = m[1]
+	} else if m := groupRegexp.FindStringSubmatchIndex(tail); m != nil && offset > 0 &&
+		!inTag(sources, tail, m[0]) && !inTag(audios, tail, m[0]) { // e.g. "WEB-DL" or "DTS-HD", not a group
+		release.Group = tail[m[2]:m[3]]
+	}
+
+	release.Title = cleanTitle(normalized[:end])
+	return release
+}
+
+// Height returns the resolution as the number of lines (e.g. 1080), or zero if it is unknown.
+func (release Release) Height() int {
+	height, _ := strconv.Atoi(strings.TrimSuffix(release.Resolution, "p"))
+	return height
+}
+
+// IsCam reports whether the release is recorded in a cinema (CAM, TS, TC or SCR).
+func (release Release) IsCam() bool {
+	switch release.Source {
+	case "CAM", "TS", "TC", "SCR":
+		return true
+	}
+	return false
+}
+
+// matchTag returns the value of the first tag found in name.
+func matchTag(tags []tag, name string, found func([]int)) string {
+	for _, t := range tags {
+		if m := t.re.FindStringIndex(name); m != nil {
+			found(m)
+			return t.value
+		}
+	}
+	return ""
+}
+
+// inTag reports whether the position pos of name is inside a match of any of the tags.
+func inTag(tags []tag, name string, pos int) bool {
+	for _, t := range tags {
+		for _, m := range t.re.FindAllStringIndex(name, -1) {
+			if m[0] <= pos && pos < m[1] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cleanTitle removes the leading release group and separators from a title.
+func cleanTitle(title string) string {
+	title = animeGroupRegexp.ReplaceAllString(title, "")
+	title = separatorRegexp.ReplaceAllString(title, " ")
+	return strings.Trim(title, " -([")
+}
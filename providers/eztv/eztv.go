@@ -0,0 +1,193 @@
+package eztv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/parser"
+	"github.com/tnychn/torrodle/registry"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
+)
+
+const (
+	Name = "EZTV"
+	Site = "https://eztv.re"
+
+	suggestionURL = "https://v2.sg.media-imdb.com/suggestion/%v/%v.json"
+	perPage       = 100
+	maxPages      = 10 // the API returns the newest torrents first, older seasons may need many pages
+)
+
+type provider struct {
+	models.Provider
+}
+
+func init() {
+	registry.MustRegister(New())
+}
+
+func New() models.ProviderInterface {
+	provider := &provider{}
+	provider.Name = Name
+	provider.Site = Site
+	provider.Mirrors = []string{
+		"https://eztv.wf",
+		"https://eztv.tf",
+		"https://eztv.yt",
+	}
+	provider.Categories = models.Categories{
+		All: "/api/get-torrents?imdb_id=%v&limit=100&page=%d",
+		TV:  "/api/get-torrents?imdb_id=%v&limit=100&page=%d",
+	} // this provider can only search for TV shows
+	return provider
+}
+
+type apiResponse struct {
+	TorrentsCount int `json:"torrents_count"`
+	Torrents      []struct {
+		Hash       string `json:"hash"`
+		EpisodeURL string `json:"episode_url"`
+		MagnetURL  string `json:"magnet_url"`
+		Title      string `json:"title"`
+		IMDbID     string `json:"imdb_id"`
+		Season     string `json:"season"`
+		Episode    string `json:"episode"`
+		Seeds      int    `json:"seeds"`
+		Peers      int    `json:"peers"`
+		Released   int64  `json:"date_released_unix"`
+		SizeBytes  string `json:"size_bytes"`
+	} `json:"torrents"`
+}
+
+// Search searches for the episodes of the TV show given by its IMDb id (e.g. "tt0944947") or its title in the query,
+// which is looked up on IMDb. A season and episode in the query (e.g. "Game of Thrones S02E05") narrow down the results.
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	var results []models.Source
+	if count <= 0 {
+		return results, nil
+	}
+	if categoryURL == "" {
+		categoryURL = provider.Categories.TV
+	}
+
+	wanted := parser.Parse(utils.RemoveIMDbIDs(query))
+	imdb := utils.FindIMDbID(query)
+	if imdb == "" {
+		var err error
+		if imdb, err = LookupIMDb(ctx, wanted.Title); err != nil {
+			return results, err
+		}
+		if imdb == "" {
+			logrus.Warningf("EZTV: No TV show found on IMDb for '%v'\n", wanted.Title)
+			return results, nil
+		}
+		logrus.Debugf("EZTV: IMDb id of '%v' -> %v\n", wanted.Title, imdb)
+	}
+
+	logrus.Infoln("EZTV: Getting search results...")
+	for page := 1; page <= maxPages && len(results) < count; page++ {
+		surl := fmt.Sprintf(string(categoryURL), strings.TrimPrefix(imdb, "tt"), page)
+		response := apiResponse{}
+		err := provider.WithMirrors(ctx, func(site string) error {
+			_, resp, err := request.GetCached(ctx, nil, site+surl, nil, request.SearchTTL)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal([]byte(resp), &response); err != nil {
+				return request.LayoutChanged(site+surl, err.Error())
+			}
+			return nil
+		})
+		if err != nil {
+			if len(results) > 0 {
+				break
+			}
+			return results, err
+		}
+
+		for _, torrent := range response.Torrents {
+			season, _ := strconv.Atoi(torrent.Season)
+			episode, _ := strconv.Atoi(torrent.Episode)
+			if wanted.Season != 0 && season != wanted.Season || wanted.Episode != 0 && episode != wanted.Episode {
+				continue
+			}
+			size, _ := strconv.ParseInt(torrent.SizeBytes, 10, 64)
+			source := models.Source{
+				From:     provider.Name,
+				Title:    strings.TrimSpace(strings.TrimSuffix(torrent.Title, "EZTV")),
+				URL:      torrent.EpisodeURL,
+				Seeders:  torrent.Seeds,
+				Leechers: torrent.Peers,
+				FileSize: size,
+				Magnet:   torrent.MagnetURL,
+				InfoHash: strings.ToLower(torrent.Hash),
+				Category: "TV",
+				Release:  parser.Parse(torrent.Title),
+			}
+			if id, err := strconv.Atoi(torrent.IMDbID); err == nil && id > 0 {
+				source.IMDB = fmt.Sprintf("tt%07d", id)
+			}
+			// the season and episode given by the API are more reliable than the ones in the title
+			if season != 0 {
+				source.Release.Season = season
+				source.Release.Episode = episode
+			}
+			if torrent.Released > 0 {
+				source.UploadDate = time.Unix(torrent.Released, 0).UTC()
+			}
+			if source.Magnet == "" {
+				continue
+			}
+			results = append(results, source)
+		}
+		if page*perPage >= response.TorrentsCount {
+			break
+		}
+	}
+	logrus.Infof("EZTV: Found %d results\n", len(results))
+	if count > len(results) {
+		count = len(results)
+	}
+	return results[:count], nil
+}
+
+type suggestionResponse struct {
+	D []struct {
+		ID   string `json:"id"`
+		Kind string `json:"qid"`
+	} `json:"d"`
+}
+
+// LookupIMDb returns the IMDb id of the TV show which best matches title,
+// or an empty string if none is found.
+func LookupIMDb(ctx context.Context, title string) (string, error) {
+	title = strings.ToLower(strings.TrimSpace(title))
+	if title == "" {
+		return "", nil
+	}
+	first := string([]rune(title)[:1])
+	surl := fmt.Sprintf(suggestionURL, url.PathEscape(first), url.PathEscape(title))
+	_, resp, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL) // new shows must be found soon
+	if err != nil {
+		return "", err
+	}
+	response := suggestionResponse{}
+	if err = json.Unmarshal([]byte(resp), &response); err != nil {
+		return "", err
+	}
+	for _, suggestion := range response.D {
+		if strings.HasPrefix(suggestion.ID, "tt") && (suggestion.Kind == "tvSeries" || suggestion.Kind == "tvMiniSeries") {
+			return suggestion.ID, nil
+		}
+	}
+	return "", nil
+}
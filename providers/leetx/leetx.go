@@ -1,17 +1,21 @@
 package leetx
 
 import (
-	"fmt"
+	"context"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
 	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
 )
 
 const (
@@ -19,14 +23,26 @@ const (
 	Site = "https://1337x.to"
 )
 
+var ordinalRegexp = regexp.MustCompile(`(\d+)(st|nd|rd|th)`)
+
 type provider struct {
 	models.Provider
 }
 
+func init() {
+	registry.MustRegister(New())
+}
+
 func New() models.ProviderInterface {
 	provider := &provider{}
 	provider.Name = Name
 	provider.Site = Site
+	provider.Mirrors = []string{
+		"https://1337x.st",
+		"https://x1337x.ws",
+		"https://x1337x.eu",
+		"https://1337x.gd",
+	}
 	provider.Categories = models.Categories{
 		All:   "/search/%v/%d/",
 		Movie: "/category-search/%v/Movies/%d/",
@@ -37,28 +53,37 @@ func New() models.ProviderInterface {
 	return provider
 }
 
-func (provider *provider) Search(query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
 	perPage := 40
 	if categoryURL == provider.Categories.All {
 		perPage = 20
 	}
-	results, err := provider.Query(query, categoryURL, count, perPage, 0, extractor)
+	results, err := provider.Query(ctx, query, categoryURL, count, perPage, 0, extractor)
 	return results, err
 }
 
-func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGroup) {
+func extractor(ctx context.Context, surl string, page int) ([]models.Source, error) {
 	logrus.Infof("1337x: [%d] Extracting results...\n", page)
-	_, html, err := request.Get(nil, surl, nil)
+	_, html, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
 	if err != nil {
-		logrus.Errorln(fmt.Sprintf("1337x: [%d]", page), err)
-		wg.Done()
-		return
+		return nil, err
 	}
 
 	var sources []models.Source // Temporary array for storing models.Source(s) but without magnet and torrent links
 	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
 	table := doc.Find("table.table-list.table.table-responsive.table-striped")
+	if table.Length() == 0 {
+		if strings.Contains(doc.Find("div.box-info-detail").Text(), "No results were returned") {
+			return nil, nil
+		}
+		return nil, request.LayoutChanged(surl, "table of results not found")
+	}
+	rows, parsed := 0, 0
 	table.Find("tr").Each(func(i int, tr *goquery.Selection) {
+		if tr.Find("td").Length() == 0 {
+			return // header
+		}
+		rows++
 		// title
 		title := tr.Find("td.coll-1.name").Text()
 		// seeders
@@ -70,35 +95,52 @@ func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGro
 		// filesize
 		tr.Find("td.coll-4.size").Find("span.seeds").Remove()
 		filesize, _ := humanize.ParseBytes(strings.TrimSpace(tr.Find("td.coll-4.size").Text())) // convert human words to bytes number
+		// upload date
+		date := parseDate(strings.TrimSpace(tr.Find("td.coll-date").Text()), time.Now())
+		// uploader
+		uploader := strings.TrimSpace(tr.Find("td.coll-5").Find("a").Text())
 		// url
 		URL, _ := tr.Find(`a[href^="/torrent"]`).Attr("href")
+		if title != "" && URL != "" {
+			parsed++
+		}
 		if title == "" || URL == "" || seeders == 0 {
 			return
 		}
 		// ---
 		source := models.Source{
-			From:     "1337x",
-			Title:    strings.TrimSpace(title),
-			URL:      Site + URL,
-			Seeders:  seeders,
-			Leechers: leechers,
-			FileSize: int64(filesize),
+			From:       "1337x",
+			Title:      strings.TrimSpace(title),
+			URL:        utils.SiteOf(surl) + URL,
+			Seeders:    seeders,
+			Leechers:   leechers,
+			FileSize:   int64(filesize),
+			UploadDate: date,
+			Uploader:   uploader,
 		}
 		sources = append(sources, source)
 	})
 
+	if rows > 0 && parsed == 0 {
+		return nil, request.LayoutChanged(surl, "titles not found in the rows")
+	}
+
 	logrus.Debugf("1337x: [%d] Amount of results: %d", page, len(sources))
 	logrus.Debugf("1337x: [%d] Getting sources in parallel...", page)
+	results := make([]models.Source, len(sources))
+	found := make([]bool, len(sources))
+	errs := make([]error, len(sources))
 	group := sync.WaitGroup{}
-	for _, source := range sources {
+	for i, source := range sources {
 		group.Add(1)
-		go func(source models.Source) {
+		go func(i int, source models.Source) {
+			defer group.Done()
 			var magnet string
 
-			_, html, err := request.Get(nil, source.URL, nil)
+			_, html, err := request.GetCached(ctx, nil, source.URL, nil, request.DetailTTL)
 			if err != nil {
 				logrus.Errorln(err)
-				group.Done()
+				errs[i] = err
 				return
 			}
 			doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
@@ -109,12 +151,52 @@ func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGro
 					magnet = val
 				}
 			}
+			// category and info hash
+			doc.Find("ul.list").Find("li").Each(func(_ int, li *goquery.Selection) {
+				if strings.TrimSpace(li.Find("strong").Text()) == "Category" {
+					source.Category = strings.TrimSpace(li.Find("span").Text())
+				}
+			})
+			hash := strings.ToLower(strings.TrimSpace(doc.Find("div.infohash-box").Find("span").Text()))
+			if hash == "" {
+				hash = utils.InfoHashFromMagnet(magnet)
+			}
 			// Assignment
 			source.Magnet = magnet
-			*results = append(*results, source)
-			group.Done()
-		}(source)
+			source.InfoHash = hash
+			results[i] = source
+			found[i] = true
+		}(i, source)
 	}
 	group.Wait()
-	wg.Done()
+
+	var sourcesWithMagnet []models.Source
+	for i := range results {
+		if found[i] {
+			sourcesWithMagnet = append(sourcesWithMagnet, results[i])
+		} else if err == nil {
+			err = errs[i]
+		}
+	}
+	// the detail pages may be blocked even though the page of results is not
+	if len(sourcesWithMagnet) == 0 && err != nil {
+		return nil, err
+	}
+	return sourcesWithMagnet, nil
+}
+
+// parseDate parses the upload dates shown by 1337x, such as "7am" (today), "Oct. 14th" (this year) and "Mar. 3rd '19".
+// Returns the zero time if it cannot be parsed.
+func parseDate(s string, now time.Time) time.Time {
+	s = ordinalRegexp.ReplaceAllString(strings.Replace(s, ".", "", 1), "$1")
+	if t, err := time.Parse("Jan 2 '06", s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("Jan 2", s); err == nil {
+		return t.AddDate(now.Year(), 0, 0)
+	}
+	if t, err := time.Parse("3pm", s); err == nil {
+		return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), 0, 0, 0, time.UTC)
+	}
+	return time.Time{}
 }
@@ -1,17 +1,20 @@
 package limetorrents
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
 	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
 )
 
 const (
@@ -23,10 +26,18 @@ type provider struct {
 	models.Provider
 }
 
+func init() {
+	registry.MustRegister(New())
+}
+
 func New() models.ProviderInterface {
 	provider := &provider{}
 	provider.Name = Name
 	provider.Site = Site
+	provider.Mirrors = []string{
+		"https://www.limetorrents.lol",
+		"https://www.limetorrents.pro",
+	}
 	provider.Categories = models.Categories{
 		All:   "/search/all/%v/seeds/%d",
 		Movie: "/search/movies/%v/seeds/%d",
@@ -36,33 +47,39 @@ func New() models.ProviderInterface {
 	return provider
 }
 
-func (provider *provider) Search(query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
-	results, err := provider.Query(query, categoryURL, count, 50, 1, extractor)
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	results, err := provider.Query(ctx, query, categoryURL, count, 50, 1, extractor)
 	return results, err
 }
 
-func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGroup) {
+func extractor(ctx context.Context, surl string, page int) ([]models.Source, error) {
 	logrus.Infof("LimeTorrents: [%d] Extracting results...\n", page)
-	_, html, err := request.Get(nil, surl, nil)
+	_, html, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
 	if err != nil {
-		logrus.Errorln(fmt.Sprintf("LimeTorrents: [%d]", page), err)
-		wg.Done()
-		return
+		return nil, err
 	}
 
 	var sources []models.Source
 	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
 	table := doc.Find("table.table2")
+	if table.Length() == 0 {
+		if strings.Contains(doc.Find("div#content").Text(), "Sorry, we could not find any torrents matching") {
+			return nil, nil
+		}
+		return nil, request.LayoutChanged(surl, "table of results not found")
+	}
+	rows, parsed := 0, 0
 	table.Find(`tr[bgcolor="#F4F4F4"]`).Each(func(_ int, tr *goquery.Selection) {
+		rows++
 		// title and url
-		var magnet, title, URL string
+		var magnet, hash, title, URL string
 		tr.Find("div.tt-name").Find("a").Each(func(i int, a *goquery.Selection) {
 			cls, _ := a.Attr("class")
 			if cls == "csprite_dl14" {
 				torrent, _ := a.Attr("href")
 				torrent = strings.Replace(torrent, "http://itorrents.org/torrent/", "", 1)
 				torrentFile := strings.Split(torrent, "?")[0]
-				hash := strings.TrimSuffix(torrentFile, ".torrent")
+				hash = strings.ToLower(strings.TrimSuffix(torrentFile, ".torrent"))
 				magnet = fmt.Sprintf("magnet:?xt=urn:btih:%v", hash)
 			} else {
 				title = strings.TrimSpace(a.Text())
@@ -71,6 +88,13 @@ func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGro
 		})
 		// filesize
 		filesize, _ := humanize.ParseBytes(strings.TrimSpace(tr.Find("td.tdnormal").Eq(1).Text())) // convert human words to bytes number
+		// upload date and category, e.g. "2 years ago - in Movies"
+		added := strings.SplitN(tr.Find("td.tdnormal").Eq(0).Text(), "- in", 2)
+		date := utils.ParseTimeAgo(added[0], time.Now())
+		var category string
+		if len(added) == 2 {
+			category = strings.TrimSpace(added[1])
+		}
 		// seeders
 		s := tr.Find("td.tdseed").Text()
 		seeders, _ := strconv.Atoi(strings.Replace(s, ",", "", -1))
@@ -78,22 +102,31 @@ func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGro
 		l := tr.Find("td.tdleech").Text()
 		leechers, _ := strconv.Atoi(strings.Replace(l, ",", "", -1))
 		// ---
+		if title != "" && URL != "" {
+			parsed++
+		}
 		if title == "" || URL == "" || seeders == 0 {
 			return
 		}
 		source := models.Source{
-			From:     "LimeTorrents",
-			Title:    title,
-			URL:      Site + URL,
-			Seeders:  seeders,
-			Leechers: leechers,
-			FileSize: int64(filesize),
-			Magnet:   magnet,
+			From:       "LimeTorrents",
+			Title:      title,
+			URL:        utils.SiteOf(surl) + URL,
+			Seeders:    seeders,
+			Leechers:   leechers,
+			FileSize:   int64(filesize),
+			Magnet:     magnet,
+			InfoHash:   hash,
+			UploadDate: date,
+			Category:   category,
 		}
 		sources = append(sources, source)
 	})
 
+	if rows > 0 && parsed == 0 {
+		return nil, request.LayoutChanged(surl, "titles not found in the rows")
+	}
+
 	logrus.Debugf("LimeTorrents: [%d] Amount of results: %d", page, len(sources))
-	*results = append(*results, sources...)
-	wg.Done()
+	return sources, nil
 }
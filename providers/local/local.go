@@ -0,0 +1,164 @@
+// Package local implements a provider which searches a local directory of .torrent files,
+// e.g. the ones downloaded from private trackers.
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/utils"
+)
+
+const Name = "Local"
+
+type entry struct {
+	modTime time.Time
+	source  models.Source
+}
+
+type provider struct {
+	models.Provider
+	dir string
+
+	mutex sync.Mutex
+	index map[string]entry // path -> parsed .torrent file, reparsed when modified
+}
+
+// New returns a provider which searches the .torrent files in dir and its sub-directories.
+func New(dir string) models.ProviderInterface {
+	provider := &provider{dir: dir, index: make(map[string]entry)}
+	provider.Name = Name
+	provider.Site = "file://" + dir
+	// the directory is searched for every category
+	provider.Categories = models.Categories{
+		All:   "*",
+		Movie: "*",
+		TV:    "*",
+		Anime: "*",
+		Porn:  "*",
+	}
+	return provider
+}
+
+// Search returns the torrents whose names (or the names of their .torrent files) contain every word of the query.
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	var results []models.Source
+	if count <= 0 {
+		return results, nil
+	}
+	logrus.Infof("Local: Indexing %v...\n", provider.dir)
+	sources, err := provider.scan(ctx)
+	if err != nil {
+		return results, err
+	}
+	for _, source := range sources {
+		if matches(source, query) {
+			results = append(results, source)
+		}
+	}
+	logrus.Infof("Local: Found %d results\n", len(results))
+	if count > len(results) {
+		count = len(results)
+	}
+	return results[:count], nil
+}
+
+// scan walks the directory and returns the sources of all the .torrent files in it.
+// Files which cannot be parsed are skipped.
+func (provider *provider) scan(ctx context.Context) ([]models.Source, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	var sources []models.Source
+	found := make(map[string]bool)
+	err := filepath.Walk(provider.dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == provider.dir {
+				return err
+			}
+			logrus.Warningf("Local: %v\n", err)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(path), ".torrent") {
+			return nil
+		}
+		found[path] = true
+		if e, ok := provider.index[path]; ok && e.modTime.Equal(info.ModTime()) {
+			sources = append(sources, e.source)
+			return nil
+		}
+		source, err := parse(path)
+		if err != nil {
+			logrus.Warningf("Local: %v: %v\n", path, err)
+			return nil
+		}
+		if source.UploadDate.IsZero() {
+			source.UploadDate = info.ModTime()
+		}
+		provider.index[path] = entry{modTime: info.ModTime(), source: source}
+		sources = append(sources, source)
+		return nil
+	})
+	if err != nil {
+		return sources, err
+	}
+	for path := range provider.index {
+		if !found[path] {
+			delete(provider.index, path)
+		}
+	}
+	return sources, nil
+}
+
+// parse parses the .torrent file at path.
+func parse(path string) (models.Source, error) {
+	mi, err := metainfo.LoadFromFile(path)
+	if err != nil {
+		return models.Source{}, err
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return models.Source{}, err
+	}
+	hash := mi.HashInfoBytes()
+	abs, _ := filepath.Abs(path)
+
+	source := models.Source{
+		From:     Name,
+		Title:    info.Name,
+		URL:      "file://" + filepath.ToSlash(abs),
+		FileSize: info.TotalLength(),
+		Magnet:   mi.Magnet(info.Name, hash).String(),
+		InfoHash: hash.HexString(),
+	}
+	if mi.CreationDate > 0 {
+		source.UploadDate = time.Unix(mi.CreationDate, 0).UTC()
+	}
+	for _, file := range info.UpvertedFiles() {
+		source.Files = append(source.Files, models.File{
+			Path:   file.DisplayPath(&info),
+			Length: file.Length,
+		})
+	}
+	return source, nil
+}
+
+// matches reports whether the name of the torrent or of its .torrent file contains every word of the query.
+func matches(source models.Source, query string) bool {
+	file := filepath.Base(strings.TrimPrefix(source.URL, "file://"))
+	return utils.ContainsWords(source.Title, query) || utils.ContainsWords(file, query)
+}
@@ -0,0 +1,167 @@
+package nyaa
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
+)
+
+const (
+	Name = "Nyaa"
+	Site = "https://nyaa.si"
+)
+
+// Sub-categories of anime on nyaa.si, searched in the Anime category instead of all anime.
+const (
+	AnimeEnglish    = "1_2" // English-translated
+	AnimeNonEnglish = "1_3" // translated in other languages
+	AnimeRaw        = "1_4" // raw
+	AnimeMusicVideo = "1_1" // anime music videos
+)
+
+// Filters of nyaa.si.
+const (
+	FilterNone      = 0
+	FilterNoRemakes = 1
+	FilterTrusted   = 2 // results of trusted uploaders only
+)
+
+// Options narrow down the searches of the provider, e.g. in the Nyaa section of the config.
+type Options struct {
+	Anime  string `json:"Anime"`  // sub-category of anime (e.g. AnimeEnglish), all anime if empty
+	Filter int    `json:"Filter"` // FilterNone, FilterNoRemakes or FilterTrusted
+	Batch  bool   `json:"Batch"`  // only batches of whole seasons or series, whose titles are matched since nyaa.si has no category for them
+}
+
+// batchRegexp matches the titles of batches, e.g. "[Batch]", "01-12", "01 ~ 24" or "Complete".
+var batchRegexp = regexp.MustCompile(`(?i)\bbatch\b|\b\d{1,3} ?[-~] ?\d{1,3}\b|\bcomplete\b`)
+
+type provider struct {
+	models.Provider
+	batch bool
+}
+
+func init() {
+	registry.MustRegister(New())
+}
+
+func New() models.ProviderInterface {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions returns a provider whose searches are narrowed down by options.
+func NewWithOptions(options Options) models.ProviderInterface {
+	provider := &provider{batch: options.Batch}
+	provider.Name = Name
+	provider.Site = Site
+	anime := options.Anime
+	if anime == "" {
+		anime = "1_0"
+	}
+	provider.Categories = models.Categories{
+		All:   models.CategoryURL(fmt.Sprintf("/?f=%d&c=0_0&q=%%v&s=seeders&o=desc&p=%%d", options.Filter)),
+		Anime: models.CategoryURL(fmt.Sprintf("/?f=%d&c=%v&q=%%v&s=seeders&o=desc&p=%%d", options.Filter, anime)),
+	}
+	return provider
+}
+
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	results, err := provider.Query(ctx, query, categoryURL, count, 75, 1, Extractor(Name))
+	if !provider.batch {
+		return results, err
+	}
+	var batches []models.Source
+	for _, source := range results {
+		if batchRegexp.MatchString(source.Title) {
+			batches = append(batches, source)
+		}
+	}
+	return batches, err
+}
+
+// Extractor returns the extractor of the sites which share the layout of nyaa.si, such as sukebei.nyaa.si.
+func Extractor(name string) models.Extractor {
+	return func(ctx context.Context, surl string, page int) ([]models.Source, error) {
+		logrus.Infof("%v: [%d] Extracting results...\n", name, page)
+		_, html, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
+		if err != nil {
+			return nil, err
+		}
+		var sources []models.Source
+		doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+		table := doc.Find("table.table.table-bordered.table-hover.table-striped.torrent-list")
+		if table.Length() == 0 {
+			if strings.Contains(doc.Find("div.container h3").Text(), "No results found") {
+				return nil, nil
+			}
+			return nil, request.LayoutChanged(surl, "table of results not found")
+		}
+		rows := 0
+		// rows are "default", "success" (trusted) or "danger" (remake)
+		table.Find("tbody tr").Each(func(i int, tr *goquery.Selection) {
+			rows++
+			tds := tr.Find("td.text-center")
+			a := tr.Find("td[colspan]").Find("a").Not(".comments").Last()
+			// title
+			title := a.Text()
+			// url
+			URL, _ := a.Attr("href")
+			// seeders
+			s := tds.Eq(3).Text()
+			seeders, _ := strconv.Atoi(strings.TrimSpace(s))
+			// leechers
+			l := tds.Eq(4).Text()
+			leechers, _ := strconv.Atoi(strings.TrimSpace(l))
+			// filesize
+			fs := tds.Eq(1).Text()
+			filesize, _ := humanize.ParseBytes(strings.TrimSpace(fs)) // convert human words to bytes number
+			// magnet
+			magnet, _ := tds.Eq(0).Find(`a[href^="magnet:"]`).Attr("href")
+			// upload date
+			var date time.Time
+			if ts, ok := tds.Eq(2).Attr("data-timestamp"); ok {
+				if unix, err := strconv.ParseInt(ts, 10, 64); err == nil {
+					date = time.Unix(unix, 0).UTC()
+				}
+			}
+			// category
+			category, _ := tr.Find("td").First().Find("a").Attr("title")
+			if title == "" || magnet == "" {
+				return
+			}
+			// ---
+			source := models.Source{
+				From:       name,
+				Title:      strings.TrimSpace(title),
+				URL:        utils.SiteOf(surl) + URL,
+				Seeders:    seeders,
+				Leechers:   leechers,
+				FileSize:   int64(filesize),
+				Magnet:     magnet,
+				InfoHash:   utils.InfoHashFromMagnet(magnet),
+				UploadDate: date,
+				Category:   category,
+				Trusted:    tr.HasClass("success"),
+				Remake:     tr.HasClass("danger"),
+			}
+			sources = append(sources, source)
+		})
+		if rows > 0 && len(sources) == 0 {
+			return nil, request.LayoutChanged(surl, "titles or magnets not found in the rows")
+		}
+		logrus.Debugf("%v: [%d] Amount of results: %d", name, page, len(sources))
+		return sources, nil
+	}
+}
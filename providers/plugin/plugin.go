@@ -0,0 +1,314 @@
+// Package plugin implements providers which run external executables, so that providers can be
+// written in any language without recompiling torrodle.
+//
+// The protocol is JSON lines over stdin and stdout. torrodle writes a single Request line to stdin
+// of the plugin and closes it, then the plugin writes Response lines to stdout and exits.
+// Anything the plugin writes to stderr goes to the logs.
+//
+// When loaded, a plugin is asked for its Info with a request of type "info".
+// Searches are requests of type "search", answered with a line for each source or error.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
+)
+
+const (
+	RequestInfo   = "info"
+	RequestSearch = "search"
+)
+
+// DefaultTimeout is the time limit of a plugin run if none is given.
+const DefaultTimeout = 30 * time.Second
+
+// Request is written to stdin of a plugin.
+type Request struct {
+	Type     string `json:"Type"`
+	Query    string `json:"Query,omitempty"`
+	Count    int    `json:"Count,omitempty"`
+	Category string `json:"Category,omitempty"` // All, Movie, TV, Anime or Porn
+}
+
+// Response is a line written to stdout of a plugin, with one of its fields set.
+type Response struct {
+	Info   *Info   `json:"Info,omitempty"`
+	Source *Source `json:"Source,omitempty"`
+	Error  string  `json:"Error,omitempty"`
+}
+
+// Info describes a plugin.
+type Info struct {
+	Name       string   `json:"Name"`       // name of the provider, the file name of the plugin if empty
+	Site       string   `json:"Site"`       // URL of the site
+	Categories []string `json:"Categories"` // supported categories, all of them if empty
+	NSFW       bool     `json:"NSFW"`       // whether the plugin is mostly for adult content
+}
+
+// Source is a torrent found by a plugin. Either Magnet or InfoHash must be set.
+type Source struct {
+	Title      string    `json:"Title"`
+	URL        string    `json:"URL"`
+	Seeders    int       `json:"Seeders"`
+	Leechers   int       `json:"Leechers"`
+	Size       int64     `json:"Size"` // in bytes
+	Magnet     string    `json:"Magnet"`
+	InfoHash   string    `json:"InfoHash"`
+	UploadDate time.Time `json:"UploadDate"` // RFC 3339
+	Uploader   string    `json:"Uploader"`
+	Category   string    `json:"Category"`
+}
+
+var categoryNames = []string{"All", "Movie", "TV", "Anime", "Porn"}
+
+type provider struct {
+	models.Provider
+	path    string
+	timeout time.Duration
+}
+
+// New returns a provider which runs the executable at path, and asks it for its Info.
+// The plugin is killed if a run takes longer than timeout.
+func New(path string, timeout time.Duration) (models.ProviderInterface, error) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	provider := &provider{path: path, timeout: timeout}
+	provider.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var info *Info
+	err := provider.run(context.Background(), Request{Type: RequestInfo}, func(resp Response) {
+		if resp.Info != nil {
+			info = resp.Info
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plugin %v: %v", provider.Name, err)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("plugin %v: no info", provider.Name)
+	}
+
+	if info.Name != "" {
+		provider.Name = info.Name
+	}
+	provider.Site = info.Site
+	provider.NSFW = info.NSFW
+	supported := info.Categories
+	if len(supported) == 0 {
+		supported = categoryNames
+	}
+	// the category URL of a plugin is the name of the category, which is sent in the request
+	categoryURL := func(name string) models.CategoryURL {
+		for _, category := range supported {
+			if strings.EqualFold(category, name) {
+				return models.CategoryURL(name)
+			}
+		}
+		return ""
+	}
+	provider.Categories = models.Categories{
+		All:   categoryURL("All"),
+		Movie: categoryURL("Movie"),
+		TV:    categoryURL("TV"),
+		Anime: categoryURL("Anime"),
+		Porn:  categoryURL("Porn"),
+	}
+	return provider, nil
+}
+
+// LoadDir returns the providers of all the executables in dir.
+// Nothing is returned if dir does not exist.
+func LoadDir(dir string, timeout time.Duration) ([]models.ProviderInterface, error) {
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var providers []models.ProviderInterface
+	for _, file := range files {
+		if !file.Mode().IsRegular() || file.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		provider, err := New(filepath.Join(dir, file.Name()), timeout)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	var results []models.Source
+	if count <= 0 {
+		return results, nil
+	}
+	if categoryURL == "" {
+		categoryURL = provider.Categories.All
+	}
+
+	logrus.Infof("%v: Running plugin...\n", provider.Name)
+	var errs []string
+	request := Request{Type: RequestSearch, Query: query, Count: count, Category: string(categoryURL)}
+	err := provider.run(ctx, request, func(resp Response) {
+		if resp.Error != "" {
+			logrus.Errorf("%v: %v\n", provider.Name, resp.Error)
+			errs = append(errs, resp.Error)
+		}
+		if resp.Source != nil {
+			if source, ok := provider.toSource(*resp.Source); ok {
+				results = append(results, source)
+			}
+		}
+	})
+	if len(results) == 0 {
+		if err != nil {
+			return results, err
+		}
+		if len(errs) > 0 {
+			return results, errors.New(strings.Join(errs, "; "))
+		}
+	} else if err != nil {
+		logrus.Errorf("%v: %v\n", provider.Name, err)
+	}
+	logrus.Infof("%v: Found %d results\n", provider.Name, len(results))
+	if count > len(results) {
+		count = len(results)
+	}
+	return results[:count], nil
+}
+
+// env returns the environment of the plugin, with the proxy of its site (if any) set in the variables understood by most HTTP clients.
+func (provider *provider) env() []string {
+	env := os.Environ()
+	host := ""
+	if u, err := url.Parse(provider.Site); err == nil {
+		host = u.Host
+	}
+	if proxy := request.ProxyOf(host); proxy != nil {
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "http_proxy", "https_proxy", "all_proxy"} {
+			env = append(env, name+"="+proxy.String())
+		}
+	}
+	return env
+}
+
+// run runs the plugin with the request and calls handle for each response.
+func (provider *provider) run(ctx context.Context, request Request, handle func(Response)) error {
+	ctx, cancel := context.WithTimeout(ctx, provider.timeout)
+	defer cancel()
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, provider.path)
+	cmd.Stdin = strings.NewReader(string(data) + "\n")
+	cmd.Env = provider.env()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		provider.log(stderr)
+	}()
+	// the pipes may be held open by children of the killed plugin, close them to stop reading
+	finished := make(chan struct{})
+	defer close(finished)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = stdout.Close()
+			_ = stderr.Close()
+		case <-finished:
+		}
+	}()
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var resp Response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			logrus.Warningf("%v: Invalid response: %v\n", provider.Name, err)
+			continue
+		}
+		handle(resp)
+	}
+	<-done
+
+	err = cmd.Wait()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %v", provider.timeout)
+	} else if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// log writes the lines of the stderr of the plugin to the logs.
+func (provider *provider) log(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		logrus.Debugf("%v: %v\n", provider.Name, scanner.Text())
+	}
+}
+
+// toSource converts a source of the plugin, sources without a magnet uri or info hash are skipped.
+func (provider *provider) toSource(s Source) (models.Source, bool) {
+	hash := strings.ToLower(s.InfoHash)
+	if hash == "" {
+		hash = utils.InfoHashFromMagnet(s.Magnet)
+	}
+	magnet := s.Magnet
+	if magnet == "" && hash != "" {
+		magnet = fmt.Sprintf("magnet:?xt=urn:btih:%v&dn=%v", hash, url.QueryEscape(s.Title))
+	}
+	if s.Title == "" || magnet == "" {
+		return models.Source{}, false
+	}
+	return models.Source{
+		From:       provider.Name,
+		Title:      strings.TrimSpace(s.Title),
+		URL:        s.URL,
+		Seeders:    s.Seeders,
+		Leechers:   s.Leechers,
+		FileSize:   s.Size,
+		Magnet:     magnet,
+		InfoHash:   hash,
+		UploadDate: s.UploadDate,
+		Uploader:   s.Uploader,
+		Category:   s.Category,
+	}, true
+}
@@ -1,6 +1,7 @@
 package rarbg
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,11 +9,14 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
 	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
 )
 
 const (
@@ -32,6 +36,10 @@ type provider struct {
 	models.Provider
 }
 
+func init() {
+	registry.MustRegister(New())
+}
+
 func New() models.ProviderInterface {
 	provider := &provider{}
 	provider.Name = Name
@@ -58,10 +66,12 @@ type apiResponse struct {
 		Leechers int    `json:"leechers"`
 		Size     int64  `json:"size"`
 		InfoPage string `json:"info_page"`
+		Category string `json:"category"`
+		PubDate  string `json:"pubdate"`
 	} `json:"torrent_results"`
 }
 
-func (provider *provider) Search(query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
 	var results []models.Source
 	if count <= 0 {
 		return results, nil
@@ -86,7 +96,7 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 
 	if _, err := os.Stat(tokenFile); os.IsNotExist(err) {
 		// rarbg_token.txt does not exist -> get a new token
-		token, err = newToken()
+		token, err = newToken(ctx)
 		if err != nil {
 			return results, err
 		}
@@ -95,7 +105,7 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 		token := getToken()
 		// file is empty -> get a new token
 		if token == "" {
-			token, err = newToken()
+			token, err = newToken(ctx)
 			if err != nil {
 				return results, err
 			}
@@ -107,23 +117,23 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 	logrus.Debugf("RARBG: surl=%v\n", surl)
 
 	logrus.Infoln("RARBG: Getting search results...")
-	_, resp, err := request.Get(nil, surl, nil)
+	_, resp, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
 	if err != nil {
 		return results, err
 	}
 	if resp == "" {
 		// empty response -> update token
-		token, err = newToken()
+		token, err = newToken(ctx)
 		if err != nil {
 			return results, err
 		}
 		// retry with the new updated token
-		return provider.Search(query, count, categoryURL)
+		return provider.Search(ctx, query, count, categoryURL)
 	}
 
 	response := apiResponse{}
 	if err = json.Unmarshal([]byte(resp), &response); err != nil {
-		return results, err
+		return results, request.LayoutChanged(surl, err.Error())
 	}
 	logrus.Infoln("RARBG: Extracting sources...")
 	data := response.TorrentResults
@@ -136,6 +146,11 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 			Leechers: result.Leechers,
 			FileSize: result.Size,
 			Magnet:   result.Download,
+			InfoHash: utils.InfoHashFromMagnet(result.Download),
+			Category: result.Category,
+		}
+		if date, err := time.Parse("2006-01-02 15:04:05 -0700", result.PubDate); err == nil {
+			source.UploadDate = date
 		}
 		if source.Title == "" || source.URL == "" || source.Seeders == 0 {
 			continue
@@ -149,9 +164,9 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 	return results[:count], nil
 }
 
-func newToken() (string, error) {
+func newToken(ctx context.Context) (string, error) {
 	logrus.Infoln("RARBG: Getting API token...")
-	_, resp, err := request.Get(nil, tokenURL, nil)
+	_, resp, err := request.Get(ctx, nil, tokenURL, nil)
 	if err != nil {
 		return "", err
 	}
@@ -159,7 +174,7 @@ func newToken() (string, error) {
 		Token string `json:"token"`
 	}{}
 	if err = json.Unmarshal([]byte(resp), &response); err != nil {
-		return "", err
+		return "", request.LayoutChanged(tokenURL, err.Error())
 	}
 	token := response.Token
 	if token == "" {
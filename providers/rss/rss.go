@@ -0,0 +1,331 @@
+// Package rss implements providers which read the RSS 2.0 or Atom feeds of trackers.
+// Besides the standard elements, the torrent:* (ezRSS) and nyaa:* namespaces are understood.
+package rss
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
+)
+
+// maxSeen is the number of items remembered by Watch, far more than the feeds show at once.
+const maxSeen = 10000
+
+// Config configures the feeds of a provider.
+type Config struct {
+	Name       string   `json:"Name"`       // unique name of the provider
+	URLs       []string `json:"URLs"`       // URLs of the feeds
+	Categories []string `json:"Categories"` // categories (Movie, TV, Anime or Porn) the feeds are offered for, besides All
+	NSFW       bool     `json:"NSFW"`       // whether the feeds are mostly for adult content
+}
+
+// Validate checks whether config is complete.
+func (config Config) Validate() error {
+	switch {
+	case config.Name == "":
+		return errors.New("rss: missing Name")
+	case len(config.URLs) == 0:
+		return fmt.Errorf("rss %v: missing URLs", config.Name)
+	}
+	return nil
+}
+
+// Feeds is a provider which searches the items of its feeds, and can watch them for new items.
+type Feeds struct {
+	models.Provider
+	urls []string
+}
+
+// New returns a provider which reads the feeds of config.
+func New(config Config) *Feeds {
+	feeds := &Feeds{urls: config.URLs}
+	feeds.Name = config.Name
+	if len(config.URLs) > 0 {
+		if u, err := url.Parse(config.URLs[0]); err == nil {
+			feeds.Site = u.Scheme + "://" + u.Host
+		}
+	}
+	feeds.NSFW = config.NSFW
+	// the category URL of the feeds is the name of the category, since all the feeds are read anyway
+	categoryURL := func(name string) models.CategoryURL {
+		for _, category := range config.Categories {
+			if strings.EqualFold(category, name) {
+				return models.CategoryURL(name)
+			}
+		}
+		return ""
+	}
+	feeds.Categories = models.Categories{
+		All:   "All",
+		Movie: categoryURL("Movie"),
+		TV:    categoryURL("TV"),
+		Anime: categoryURL("Anime"),
+		Porn:  categoryURL("Porn"),
+	}
+	return feeds
+}
+
+// Search returns the items of all the feeds whose titles contain every word of the query.
+func (feeds *Feeds) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	var results []models.Source
+	if count <= 0 {
+		return results, nil
+	}
+	logrus.Infof("%v: Getting feeds...\n", feeds.Name)
+	sources, err := feeds.fetch(ctx)
+	for _, source := range sources {
+		if utils.ContainsWords(source.Title, query) {
+			results = append(results, source)
+		}
+	}
+	if len(sources) == 0 && err != nil {
+		return results, err
+	}
+	logrus.Infof("%v: Found %d results\n", feeds.Name, len(results))
+	if count > len(results) {
+		count = len(results)
+	}
+	return results[:count], nil
+}
+
+// Watch reads the feeds every interval and sends the new items whose titles contain every word of the query.
+// The items which are already in the feeds when it starts are not sent.
+// The channel is closed when ctx is done.
+func (feeds *Feeds) Watch(ctx context.Context, query string, interval time.Duration) <-chan models.Source {
+	ch := make(chan models.Source)
+	go func() {
+		defer close(ch)
+		seen := make(map[string]bool)
+		var order []string // keys of seen, oldest first
+		first := true
+		for {
+			sources, err := feeds.fetch(ctx)
+			if err != nil {
+				logrus.Errorf("%v: %v\n", feeds.Name, err)
+			}
+			for _, source := range sources {
+				key := source.InfoHash
+				if key == "" {
+					key = source.Magnet
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				order = append(order, key)
+				if len(order) > maxSeen {
+					delete(seen, order[0])
+					order = order[1:]
+				}
+				if first || !utils.ContainsWords(source.Title, query) {
+					continue
+				}
+				select {
+				case ch <- source:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err == nil {
+				first = false // the items already in the feeds are only known once they are read
+			}
+
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// fetch reads all the feeds in parallel.
+// Feeds that failed are skipped, an error is only returned if no feed succeeded.
+func (feeds *Feeds) fetch(ctx context.Context) ([]models.Source, error) {
+	sources := make([][]models.Source, len(feeds.urls))
+	errs := make([]error, len(feeds.urls))
+	wg := sync.WaitGroup{}
+	for i, surl := range feeds.urls {
+		wg.Add(1)
+		go func(i int, surl string) {
+			defer wg.Done()
+			_, resp, err := request.Get(ctx, nil, surl, nil)
+			if err == nil {
+				if sources[i], err = feeds.parse(resp); err != nil {
+					err = request.LayoutChanged(surl, err.Error())
+				}
+			}
+			if err != nil {
+				logrus.Errorf("%v: %v: %v\n", feeds.Name, surl, err)
+				errs[i] = err
+			}
+		}(i, surl)
+	}
+	wg.Wait()
+
+	var results []models.Source
+	var err error
+	for i := range sources {
+		results = append(results, sources[i]...)
+		if errs[i] != nil && err == nil {
+			err = errs[i]
+		}
+	}
+	if len(results) > 0 {
+		err = nil
+	}
+	return results, err
+}
+
+type feed struct {
+	Items   []item `xml:"channel>item"` // RSS 2.0
+	Entries []item `xml:"entry"`        // Atom
+}
+
+type link struct {
+	Href string `xml:"href,attr"`
+	Text string `xml:",chardata"`
+}
+
+type category struct {
+	Term string `xml:"term,attr"`
+	Text string `xml:",chardata"`
+}
+
+// item is an RSS item or an Atom entry, elements are matched by their names in any namespace.
+type item struct {
+	Title      string     `xml:"title"`
+	Links      []link     `xml:"link"`
+	GUID       string     `xml:"guid"`
+	ID         string     `xml:"id"`
+	Comments   string     `xml:"comments"`
+	PubDate    string     `xml:"pubDate"`
+	Published  string     `xml:"published"`
+	Updated    string     `xml:"updated"`
+	Categories []category `xml:"category"` // including nyaa:category
+	Enclosure  struct {
+		URL string `xml:"url,attr"`
+	} `xml:"enclosure"`
+
+	// torrent:*, numbers are kept as strings since some feeds leave them empty
+	ContentLength string `xml:"contentLength"`
+	InfoHash      string `xml:"infoHash"` // nyaa:infoHash as well
+	MagnetURI     string `xml:"magnetURI"`
+	Seeds         string `xml:"seeds"`
+	Peers         string `xml:"peers"`
+	// nyaa:*
+	Seeders  string `xml:"seeders"`
+	Leechers string `xml:"leechers"`
+	Size     string `xml:"size"`
+	Trusted  string `xml:"trusted"`
+	Remake   string `xml:"remake"`
+}
+
+var dateLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700"}
+
+// parse parses the items of an RSS or Atom feed, items without a magnet uri or info hash are skipped.
+func (feeds *Feeds) parse(resp string) ([]models.Source, error) {
+	f := feed{}
+	if err := xml.Unmarshal([]byte(resp), &f); err != nil {
+		return nil, err
+	}
+
+	var sources []models.Source
+	for _, item := range append(f.Items, f.Entries...) {
+		// links of RSS are texts, links of Atom are attributes
+		var links []string
+		for _, l := range item.Links {
+			links = append(links, strings.TrimSpace(l.Href), strings.TrimSpace(l.Text))
+		}
+		links = append(links, item.Enclosure.URL, item.GUID, item.ID)
+
+		magnet := item.MagnetURI
+		var page string
+		for _, l := range links {
+			switch {
+			case strings.HasPrefix(l, "magnet:") && magnet == "":
+				magnet = l
+			case strings.HasPrefix(l, "http") && !strings.HasSuffix(l, ".torrent") && page == "":
+				page = l
+			}
+		}
+		if strings.HasPrefix(item.Comments, "http") { // nyaa:comments is the number of comments
+			page = item.Comments
+		}
+		hash := strings.ToLower(item.InfoHash)
+		if hash == "" {
+			hash = utils.InfoHashFromMagnet(magnet)
+		}
+		if magnet == "" && hash != "" {
+			magnet = fmt.Sprintf("magnet:?xt=urn:btih:%v&dn=%v", hash, url.QueryEscape(item.Title))
+		}
+		if magnet == "" {
+			logrus.Debugf("%v: Skipping '%v' without magnet uri\n", feeds.Name, item.Title)
+			continue
+		}
+
+		source := models.Source{
+			From:     feeds.Name,
+			Title:    strings.TrimSpace(item.Title),
+			URL:      page,
+			Seeders:  atoi(item.Seeders) + atoi(item.Seeds),
+			Leechers: atoi(item.Leechers),
+			Magnet:   magnet,
+			InfoHash: hash,
+			Trusted:  strings.EqualFold(item.Trusted, "yes"),
+			Remake:   strings.EqualFold(item.Remake, "yes"),
+		}
+		if peers := atoi(item.Peers); peers > source.Seeders && source.Leechers == 0 {
+			source.Leechers = peers - source.Seeders // torrent:peers counts the seeders too
+		}
+		source.FileSize, _ = strconv.ParseInt(strings.TrimSpace(item.ContentLength), 10, 64)
+		if source.FileSize == 0 && item.Size != "" {
+			size, _ := humanize.ParseBytes(item.Size)
+			source.FileSize = int64(size)
+		}
+		var categories []string
+		for _, c := range item.Categories {
+			if name := strings.TrimSpace(c.Term + c.Text); name != "" {
+				categories = append(categories, name)
+			}
+		}
+		source.Category = strings.Join(categories, ", ")
+		for _, date := range []string{item.PubDate, item.Published, item.Updated} {
+			if source.UploadDate = parseDate(date); !source.UploadDate.IsZero() {
+				break
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// parseDate parses the dates of RSS and Atom, returns the zero time if it cannot be parsed.
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.Replace(strings.TrimSpace(s), ",", "", -1))
+	return n
+}
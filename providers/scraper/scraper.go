@@ -0,0 +1,321 @@
+// Package scraper implements providers which scrape the HTML pages of a site
+// according to a declarative definition, so that sites can be added or fixed without code changes.
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
+)
+
+// Field tells how to extract a single value from a row of results or a detail page.
+type Field struct {
+	Selector string `json:"Selector"` // CSS selector, the row (or the whole detail page) itself if empty
+	Attr     string `json:"Attr"`     // attribute holding the value, the text if empty
+	Regexp   string `json:"Regexp"`   // regular expression applied to the value, its first group (or whole match) is used
+	Layout   string `json:"Layout"`   // time layout of Date, relative dates such as "2 days ago" are parsed if empty
+
+	re *regexp.Regexp
+}
+
+// Fields are the fields of a source which can be extracted. Fields which are nil are not extracted.
+type Fields struct {
+	Title    *Field `json:"Title"`
+	URL      *Field `json:"URL"`
+	Seeders  *Field `json:"Seeders"`
+	Leechers *Field `json:"Leechers"`
+	Size     *Field `json:"Size"`
+	Magnet   *Field `json:"Magnet"`
+	InfoHash *Field `json:"InfoHash"`
+	Date     *Field `json:"Date"`
+	Uploader *Field `json:"Uploader"`
+	Category *Field `json:"Category"`
+}
+
+// Definition defines a scraper provider.
+type Definition struct {
+	Name       string            `json:"Name"`
+	Site       string            `json:"Site"`
+	NSFW       bool              `json:"NSFW"`
+	Categories models.Categories `json:"Categories"` // URL templates relative to Site, with %v for the query and %d for the page
+	PerPage    int               `json:"PerPage"`    // number of results per page
+	StartPage  int               `json:"StartPage"`  // number of the first page, usually 0 or 1
+	Rows       string            `json:"Rows"`       // CSS selector of the rows of results
+	NoResults  string            `json:"NoResults"`  // CSS selector of the element shown instead of the rows when nothing is found, if any
+	Fields     Fields            `json:"Fields"`     // fields extracted from each row
+	Detail     *Fields           `json:"Detail"`     // fields extracted from the detail page (at URL) of each row, if set
+}
+
+type provider struct {
+	models.Provider
+	definition Definition
+}
+
+// New returns a provider which scrapes the site of definition.
+func New(definition Definition) (models.ProviderInterface, error) {
+	if err := definition.compile(); err != nil {
+		return nil, err
+	}
+	provider := &provider{definition: definition}
+	provider.Name = definition.Name
+	provider.Site = strings.TrimRight(definition.Site, "/")
+	provider.Categories = definition.Categories
+	provider.NSFW = definition.NSFW
+	return provider, nil
+}
+
+// Load returns the provider defined in the JSON file at path.
+func Load(path string) (models.ProviderInterface, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var definition Definition
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	provider, err := New(definition)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	return provider, nil
+}
+
+// LoadDir returns the providers defined in all the JSON files in dir.
+// Nothing is returned if dir does not exist.
+func LoadDir(dir string) ([]models.ProviderInterface, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var providers []models.ProviderInterface
+	for _, path := range paths {
+		provider, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// compile validates the definition and compiles the regular expressions of its fields.
+func (definition *Definition) compile() error {
+	switch {
+	case definition.Name == "":
+		return errors.New("scraper: missing Name")
+	case definition.Site == "":
+		return fmt.Errorf("scraper %v: missing Site", definition.Name)
+	case definition.Categories.All == "":
+		return fmt.Errorf("scraper %v: missing All category", definition.Name)
+	case definition.Rows == "":
+		return fmt.Errorf("scraper %v: missing Rows", definition.Name)
+	case definition.Fields.Title == nil:
+		return fmt.Errorf("scraper %v: missing Title field", definition.Name)
+	case definition.Detail != nil && definition.Fields.URL == nil:
+		return fmt.Errorf("scraper %v: missing URL field for the detail page", definition.Name)
+	case definition.Fields.Magnet == nil && definition.Fields.InfoHash == nil && definition.Detail == nil:
+		return fmt.Errorf("scraper %v: missing Magnet or InfoHash field", definition.Name)
+	}
+	if definition.PerPage <= 0 {
+		definition.PerPage = 50
+	}
+	if err := definition.Fields.compile(); err != nil {
+		return fmt.Errorf("scraper %v: %v", definition.Name, err)
+	}
+	if definition.Detail != nil {
+		if err := definition.Detail.compile(); err != nil {
+			return fmt.Errorf("scraper %v: %v", definition.Name, err)
+		}
+	}
+	return nil
+}
+
+func (fields *Fields) compile() error {
+	for _, field := range []*Field{
+		fields.Title, fields.URL, fields.Seeders, fields.Leechers, fields.Size,
+		fields.Magnet, fields.InfoHash, fields.Date, fields.Uploader, fields.Category,
+	} {
+		if field == nil || field.Regexp == "" {
+			continue
+		}
+		re, err := regexp.Compile(field.Regexp)
+		if err != nil {
+			return err
+		}
+		field.re = re
+	}
+	return nil
+}
+
+// extract returns the value of the field in selection, or an empty string if it is not found.
+func (field *Field) extract(selection *goquery.Selection) string {
+	if field == nil {
+		return ""
+	}
+	if field.Selector != "" {
+		selection = selection.Find(field.Selector).First()
+	}
+	var value string
+	if field.Attr != "" {
+		value, _ = selection.Attr(field.Attr)
+	} else {
+		value = selection.Text()
+	}
+	value = strings.TrimSpace(value)
+	if field.re != nil {
+		match := field.re.FindStringSubmatch(value)
+		switch {
+		case match == nil:
+			value = ""
+		case len(match) > 1:
+			value = match[1]
+		default:
+			value = match[0]
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	definition := provider.definition
+	results, err := provider.Query(ctx, query, categoryURL, count, definition.PerPage, definition.StartPage, provider.extractor)
+	return results, err
+}
+
+func (provider *provider) extractor(ctx context.Context, surl string, page int) ([]models.Source, error) {
+	logrus.Infof("%v: [%d] Extracting results...\n", provider.Name, page)
+	_, html, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
+	if err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(surl)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []models.Source
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+	rows := doc.Find(provider.definition.Rows)
+	if rows.Length() == 0 && (provider.definition.NoResults == "" || doc.Find(provider.definition.NoResults).Length() == 0) {
+		return nil, request.LayoutChanged(surl, "no rows matching "+provider.definition.Rows)
+	}
+	rows.Each(func(i int, row *goquery.Selection) {
+		source := models.Source{From: provider.Name}
+		provider.fill(&source, provider.definition.Fields, row, base)
+		if source.Title == "" {
+			return
+		}
+		sources = append(sources, source)
+	})
+	if rows.Length() > 0 && len(sources) == 0 {
+		return nil, request.LayoutChanged(surl, "titles not found in the rows")
+	}
+	logrus.Debugf("%v: [%d] Amount of results: %d", provider.Name, page, len(sources))
+
+	if provider.definition.Detail != nil {
+		logrus.Debugf("%v: [%d] Getting detail pages in parallel...", provider.Name, page)
+		// detail pages are cached for long, unless the seeders are taken from them
+		ttl := request.DetailTTL
+		if provider.definition.Detail.Seeders != nil || provider.definition.Detail.Leechers != nil {
+			ttl = request.SearchTTL
+		}
+		group := sync.WaitGroup{}
+		for i := range sources {
+			if sources[i].URL == "" {
+				continue
+			}
+			group.Add(1)
+			go func(source *models.Source) {
+				defer group.Done()
+				_, html, err := request.GetCached(ctx, nil, source.URL, nil, ttl)
+				if err != nil {
+					logrus.Errorln(err)
+					return
+				}
+				base, _ := url.Parse(source.URL)
+				doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+				provider.fill(source, *provider.definition.Detail, doc.Selection, base)
+			}(&sources[i])
+		}
+		group.Wait()
+	}
+
+	var results []models.Source
+	for _, source := range sources {
+		if source.InfoHash == "" {
+			source.InfoHash = utils.InfoHashFromMagnet(source.Magnet)
+		}
+		if source.Magnet == "" && source.InfoHash != "" {
+			source.Magnet = fmt.Sprintf("magnet:?xt=urn:btih:%v&dn=%v", source.InfoHash, url.QueryEscape(source.Title))
+		}
+		if source.Magnet == "" {
+			continue
+		}
+		results = append(results, source)
+	}
+	return results, nil
+}
+
+// fill sets the fields of source which are extracted from selection, leaving the others untouched.
+// Relative URLs are resolved against base.
+func (provider *provider) fill(source *models.Source, fields Fields, selection *goquery.Selection, base *url.URL) {
+	if v := fields.Title.extract(selection); v != "" {
+		source.Title = v
+	}
+	if v := fields.URL.extract(selection); v != "" {
+		if ref, err := url.Parse(v); err == nil {
+			source.URL = base.ResolveReference(ref).String()
+		}
+	}
+	if v := fields.Seeders.extract(selection); v != "" {
+		source.Seeders, _ = strconv.Atoi(strings.Replace(v, ",", "", -1))
+	}
+	if v := fields.Leechers.extract(selection); v != "" {
+		source.Leechers, _ = strconv.Atoi(strings.Replace(v, ",", "", -1))
+	}
+	if v := fields.Size.extract(selection); v != "" {
+		size, _ := humanize.ParseBytes(v)
+		source.FileSize = int64(size)
+	}
+	if v := fields.Magnet.extract(selection); v != "" {
+		source.Magnet = v
+	}
+	if v := fields.InfoHash.extract(selection); v != "" {
+		source.InfoHash = strings.ToLower(v)
+	}
+	if v := fields.Date.extract(selection); v != "" {
+		if fields.Date.Layout == "" {
+			source.UploadDate = utils.ParseTimeAgo(v, time.Now())
+		} else if date, err := time.Parse(fields.Date.Layout, v); err == nil {
+			source.UploadDate = date
+		}
+	}
+	if v := fields.Uploader.extract(selection); v != "" {
+		source.Uploader = v
+	}
+	if v := fields.Category.extract(selection); v != "" {
+		source.Category = v
+	}
+}
@@ -1,20 +1,24 @@
 package thepiratebay
 
 import (
-	"fmt"
+	"context"
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
 	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
 )
 
+var uploadedRegexp = regexp.MustCompile(`Uploaded\s(.*?),`)
+
 const (
 	Name = "ThePirateBay"
 	Site = "https://thepiratebay.org"
@@ -24,10 +28,19 @@ type provider struct {
 	models.Provider
 }
 
+func init() {
+	registry.MustRegister(New())
+}
+
 func New() models.ProviderInterface {
 	provider := &provider{}
 	provider.Name = Name
 	provider.Site = Site
+	provider.Mirrors = []string{
+		"https://thepiratebay10.org",
+		"https://thehiddenbay.com",
+		"https://pirateproxy.live",
+	}
 	provider.Categories = models.Categories{
 		All:   "/search/%v/%d/99/0",
 		Movie: "/search/%v/%d/99/200",
@@ -37,27 +50,38 @@ func New() models.ProviderInterface {
 	return provider
 }
 
-func (provider *provider) Search(query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
-	results, err := provider.Query(query, categoryURL, count, 30, 0, extractor)
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	results, err := provider.Query(ctx, query, categoryURL, count, 30, 0, extractor)
 	return results, err
 }
 
-func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGroup) {
+func extractor(ctx context.Context, surl string, page int) ([]models.Source, error) {
 	logrus.Infof("ThePirateBay: [%d] Extracting results...\n", page)
-	_, html, err := request.Get(nil, surl, nil)
+	_, html, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
 	if err != nil {
-		logrus.Errorln(fmt.Sprintf("ThePirateBay: [%d]", page), err)
-		wg.Done()
-		return
+		return nil, err
 	}
 	var sources []models.Source
 	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
 	table := doc.Find("table#searchResult").Find("tbody")
+	if table.Length() == 0 {
+		if strings.HasPrefix(strings.TrimSpace(doc.Find("div#content h2").Text()), "No hits") {
+			return nil, nil
+		}
+		return nil, request.LayoutChanged(surl, "table of results not found")
+	}
+	rows := 0
 	table.Find("tr").Each(func(i int, tr *goquery.Selection) {
 		tds := tr.Find("td")
+		if tds.Length() > 2 {
+			rows++
+		}
 		a := tds.Eq(1).Find("a.detLink")
 		// title
 		title := a.Text()
+		if title == "" {
+			return // e.g. the row of pagination
+		}
 		// seeders
 		s := tds.Eq(2).Text()
 		seeders, _ := strconv.Atoi(strings.TrimSpace(s))
@@ -67,26 +91,78 @@ func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGro
 		// filesize
 		re := regexp.MustCompile(`Size\s(.*?),`)
 		text := tds.Eq(1).Find("font").Text()
-		fs := re.FindStringSubmatch(text)[1]
-		filesize, _ := humanize.ParseBytes(strings.TrimSpace(fs)) // convert human words to bytes number
+		var filesize uint64
+		if match := re.FindStringSubmatch(text); match != nil {
+			filesize, _ = humanize.ParseBytes(strings.TrimSpace(match[1])) // convert human words to bytes number
+		}
+		// upload date
+		var date time.Time
+		if match := uploadedRegexp.FindStringSubmatch(strings.Replace(text, "\u00a0", " ", -1)); match != nil {
+			date = parseDate(match[1], time.Now())
+		}
+		// uploader
+		desc := tds.Eq(1).Find("font.detDesc")
+		uploader := desc.Find("a.detDesc").Text()
+		if uploader == "" {
+			uploader = desc.Find("i").Text() // Anonymous
+		}
+		// category
+		var category []string
+		tds.Eq(0).Find("a").Each(func(_ int, a *goquery.Selection) {
+			category = append(category, strings.TrimSpace(a.Text()))
+		})
 		// url
 		URL, _ := a.Attr("href")
 		// magnet
 		magnet, _ := tds.Eq(1).Find(`a[title="Download this torrent using magnet"]`).Attr("href")
 		// ---
 		source := models.Source{
-			From:     "ThePirateBay",
-			Title:    strings.TrimSpace(title),
-			URL:      Site + URL,
-			Seeders:  seeders,
-			Leechers: leechers,
-			FileSize: int64(filesize),
-			Magnet:   magnet,
+			From:       "ThePirateBay",
+			Title:      strings.TrimSpace(title),
+			URL:        utils.SiteOf(surl) + URL,
+			Seeders:    seeders,
+			Leechers:   leechers,
+			FileSize:   int64(filesize),
+			Magnet:     magnet,
+			InfoHash:   utils.InfoHashFromMagnet(magnet),
+			UploadDate: date,
+			Category:   strings.Join(category, " > "),
+			Uploader:   strings.TrimSpace(uploader),
 		}
 		sources = append(sources, source)
 	})
 
+	if rows > 0 && len(sources) == 0 {
+		return nil, request.LayoutChanged(surl, "titles not found in the rows")
+	}
+
 	logrus.Debugf("ThePirateBay: [%d] Amount of results: %d", page, len(sources))
-	*results = append(*results, sources...)
-	wg.Done()
+	return sources, nil
+}
+
+// parseDate parses the upload dates shown by ThePirateBay, such as "5 mins ago", "Today 14:22", "Y-day 14:22",
+// "03-13 14:22" (this year) and "03-13 2018". Returns the zero time if it cannot be parsed.
+func parseDate(s string, now time.Time) time.Time {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "ago") {
+		return utils.ParseTimeAgo(s, now)
+	}
+	if strings.HasPrefix(s, "Today") || strings.HasPrefix(s, "Y-day") {
+		day := now
+		if strings.HasPrefix(s, "Y-day") {
+			day = now.AddDate(0, 0, -1)
+		}
+		t, err := time.Parse("15:04", strings.TrimSpace(s[5:]))
+		if err != nil {
+			return time.Time{}
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+	}
+	if t, err := time.Parse("01-02 2006", s); err == nil {
+		return t
+	}
+	if t, err := time.Parse("01-02 15:04", s); err == nil {
+		return t.AddDate(now.Year(), 0, 0)
+	}
+	return time.Time{}
 }
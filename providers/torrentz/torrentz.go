@@ -1,17 +1,20 @@
 package torrentz
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
 	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
 )
 
 const (
@@ -23,10 +26,17 @@ type provider struct {
 	models.Provider
 }
 
+func init() {
+	registry.MustRegister(New())
+}
+
 func New() models.ProviderInterface {
 	provider := &provider{}
 	provider.Name = Name
 	provider.Site = Site
+	provider.Mirrors = []string{
+		"https://torrentz2.is",
+	}
 	provider.Categories = models.Categories{
 		All:   "/search?f=%v&p=%d",
 		Movie: "/search?f=%v&p=%d",
@@ -37,23 +47,26 @@ func New() models.ProviderInterface {
 	return provider
 }
 
-func (provider *provider) Search(query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
-	results, err := provider.Query(query, categoryURL, count, 50, 0, extractor)
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	results, err := provider.Query(ctx, query, categoryURL, count, 50, 0, extractor)
 	return results, err
 }
 
-func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGroup) {
+func extractor(ctx context.Context, surl string, page int) ([]models.Source, error) {
 	logrus.Infof("Torrentz2: [%d] Extracting results...\n", page)
-	_, html, err := request.Get(nil, surl, nil)
+	_, html, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
 	if err != nil {
-		logrus.Errorln(fmt.Sprintf("Torrentz2: [%d]", page), err)
-		wg.Done()
-		return
+		return nil, err
 	}
 	var sources []models.Source
 	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
 	div := doc.Find("div.results")
+	if div.Length() == 0 { // the list is shown (empty) when nothing is found too
+		return nil, request.LayoutChanged(surl, "list of results not found")
+	}
+	rows, parsed := 0, 0
 	div.Find("dl").Each(func(i int, s *goquery.Selection) {
+		rows++
 		// title
 		title := s.Find("dt").Find("a").Text()
 
@@ -64,28 +77,47 @@ func extractor(surl string, page int, results *[]models.Source, wg *sync.WaitGro
 		seeders, _ := strconv.Atoi(spans.Eq(3).Text())
 		// leechers
 		leechers, _ := strconv.Atoi(spans.Eq(4).Text())
+		// upload date
+		var date time.Time
+		if ts, ok := spans.Eq(1).Attr("title"); ok {
+			if unix, err := strconv.ParseInt(ts, 10, 64); err == nil {
+				date = time.Unix(unix, 0).UTC()
+			}
+		}
+		// category
+		dt := s.Find("dt").Clone()
+		dt.Find("a").Remove()
+		category := strings.TrimSpace(strings.Trim(strings.TrimSpace(dt.Text()), "»"))
 		// url
 		URL, _ := s.Find("dt").Find("a").Attr("href")
 		// magnet
 		hash := strings.TrimLeft(URL, "/")
 		magnet := fmt.Sprintf("magnet:?xt=urn:btih:%v", hash)
 
+		if title != "" && URL != "" {
+			parsed++
+		}
 		if title == "" || URL == "" || seeders == 0 {
 			return
 		}
 		// ---
 		source := models.Source{
-			From:     "Torrentz2",
-			Title:    strings.TrimSpace(title),
-			URL:      Site + URL,
-			Seeders:  seeders,
-			Leechers: leechers,
-			FileSize: int64(filesize),
-			Magnet:   magnet,
+			From:       "Torrentz2",
+			Title:      strings.TrimSpace(title),
+			URL:        utils.SiteOf(surl) + URL,
+			Seeders:    seeders,
+			Leechers:   leechers,
+			FileSize:   int64(filesize),
+			Magnet:     magnet,
+			InfoHash:   strings.ToLower(hash),
+			UploadDate: date,
+			Category:   category,
 		}
 		sources = append(sources, source)
 	})
+	if rows > 0 && parsed == 0 {
+		return nil, request.LayoutChanged(surl, "titles not found in the rows")
+	}
 	logrus.Debugf("Torrentz2: [%d] Amount of results: %d", page, len(sources))
-	*results = append(*results, sources...)
-	wg.Done()
+	return sources, nil
 }
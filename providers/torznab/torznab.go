@@ -0,0 +1,227 @@
+package torznab
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/request"
+	"github.com/tnychn/torrodle/utils"
+)
+
+const (
+	perPage  = 100
+	maxPages = 10 // some indexers ignore the offset and return the same page again
+)
+
+// defaultCategories are the standard Torznab category ids of each category.
+var defaultCategories = map[string][]int{
+	"All":   {2000, 5000, 6000},
+	"Movie": {2000},
+	"TV":    {5000},
+	"Anime": {5070},
+	"Porn":  {6000},
+}
+
+// Config configures a Torznab indexer, e.g. of Jackett or Prowlarr.
+type Config struct {
+	Name       string           `json:"Name"`       // unique name of the provider
+	URL        string           `json:"URL"`        // URL of the Torznab API, e.g. "http://localhost:9117/api/v2.0/indexers/all/results/torznab"
+	APIKey     string           `json:"APIKey"`     // API key of the indexer
+	Categories map[string][]int `json:"Categories"` // category (All, Movie, TV, Anime or Porn) -> Torznab category ids
+	NSFW       bool             `json:"NSFW"`       // whether the indexer is mostly for adult content
+}
+
+type provider struct {
+	models.Provider
+	apiKey string
+}
+
+// New returns a provider which searches the Torznab indexer of config.
+func New(config Config) models.ProviderInterface {
+	provider := &provider{apiKey: config.APIKey}
+	provider.Name = config.Name
+	provider.Site = strings.TrimRight(config.URL, "/")
+	provider.NSFW = config.NSFW
+
+	categoryURL := func(name string) models.CategoryURL {
+		ids := defaultCategories[name]
+		for key, mapped := range config.Categories {
+			if strings.EqualFold(key, name) {
+				ids = mapped
+			}
+		}
+		var cats []string
+		for _, id := range ids {
+			cats = append(cats, strconv.Itoa(id))
+		}
+		return models.CategoryURL("?t=search&cat=" + strings.Join(cats, ",") + "&q=%v&offset=%d")
+	}
+	provider.Categories = models.Categories{
+		All:   categoryURL("All"),
+		Movie: categoryURL("Movie"),
+		TV:    categoryURL("TV"),
+		Anime: categoryURL("Anime"),
+		Porn:  categoryURL("Porn"),
+	}
+	return provider
+}
+
+type rss struct {
+	Channel struct {
+		Items []item `xml:"item"`
+	} `xml:"channel"`
+}
+
+type apiError struct {
+	Code        string `xml:"code,attr"`
+	Description string `xml:"description,attr"`
+}
+
+type item struct {
+	Title    string   `xml:"title"`
+	GUID     string   `xml:"guid"`
+	Link     string   `xml:"link"`
+	Comments string   `xml:"comments"`
+	PubDate  string   `xml:"pubDate"`
+	Size     int64    `xml:"size"`
+	Category []string `xml:"category"`
+	Attrs    []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	} `xml:"attr"`
+}
+
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+	var results []models.Source
+	if count <= 0 {
+		return results, nil
+	}
+	if categoryURL == "" {
+		categoryURL = provider.Categories.All
+	}
+	escaped := url.QueryEscape(query)
+
+	logrus.Infof("%v: Getting search results...\n", provider.Name)
+	seen := make(map[string]bool)
+	for page := 0; page < maxPages && len(results) < count; page++ {
+		offset := page * perPage
+		purl := provider.Site + fmt.Sprintf(string(categoryURL), escaped, offset) // without the API key, for errors
+		surl := purl + fmt.Sprintf("&limit=%d&apikey=%v", perPage, url.QueryEscape(provider.apiKey))
+		_, resp, err := request.GetCached(ctx, nil, surl, nil, request.SearchTTL)
+		if err != nil {
+			if len(results) > 0 {
+				break
+			}
+			return results, err
+		}
+		items, err := parse(purl, resp)
+		if err != nil {
+			return results, err
+		}
+		added := 0
+		for _, item := range items {
+			source, ok := provider.toSource(item)
+			if !ok || seen[source.InfoHash+source.Magnet] {
+				continue
+			}
+			seen[source.InfoHash+source.Magnet] = true
+			results = append(results, source)
+			added++
+		}
+		if len(items) < perPage || added == 0 {
+			break
+		}
+	}
+	logrus.Infof("%v: Found %d results\n", provider.Name, len(results))
+	if count > len(results) {
+		count = len(results)
+	}
+	return results[:count], nil
+}
+
+// parse parses the items of a Torznab response of surl.
+func parse(surl string, resp string) ([]item, error) {
+	e := apiError{}
+	if err := xml.Unmarshal([]byte(resp), &e); err == nil && e.Description != "" {
+		return nil, fmt.Errorf("torznab error %v: %v", e.Code, e.Description)
+	}
+	feed := rss{}
+	if err := xml.Unmarshal([]byte(resp), &feed); err != nil {
+		return nil, request.LayoutChanged(surl, err.Error())
+	}
+	return feed.Channel.Items, nil
+}
+
+// toSource converts an item into a source, items without a magnet uri or info hash are skipped.
+func (provider *provider) toSource(item item) (models.Source, bool) {
+	attrs := make(map[string]string)
+	for _, attr := range item.Attrs {
+		attrs[attr.Name] = attr.Value
+	}
+	seeders, _ := strconv.Atoi(attrs["seeders"])
+	peers, _ := strconv.Atoi(attrs["peers"])
+	size := item.Size
+	if s, err := strconv.ParseInt(attrs["size"], 10, 64); err == nil && size == 0 {
+		size = s
+	}
+
+	magnet := attrs["magneturl"]
+	if magnet == "" && strings.HasPrefix(item.Link, "magnet:") {
+		magnet = item.Link
+	}
+	hash := strings.ToLower(attrs["infohash"])
+	if hash == "" {
+		hash = utils.InfoHashFromMagnet(magnet)
+	}
+	if magnet == "" && hash != "" {
+		magnet = fmt.Sprintf("magnet:?xt=urn:btih:%v&dn=%v", hash, url.QueryEscape(item.Title))
+	}
+	if magnet == "" {
+		logrus.Debugf("%v: Skipping '%v' without magnet uri\n", provider.Name, item.Title)
+		return models.Source{}, false
+	}
+
+	source := models.Source{
+		From:     provider.Name,
+		Title:    strings.TrimSpace(item.Title),
+		URL:      item.Comments,
+		Seeders:  seeders,
+		Leechers: peers - seeders,
+		FileSize: size,
+		Magnet:   magnet,
+		InfoHash: hash,
+		Category: strings.Join(item.Category, ","),
+	}
+	if source.URL == "" {
+		source.URL = item.GUID
+	}
+	if source.Leechers < 0 {
+		source.Leechers = 0
+	}
+	if date, err := time.Parse(time.RFC1123Z, item.PubDate); err == nil {
+		source.UploadDate = date
+	} else if date, err := time.Parse(time.RFC1123, item.PubDate); err == nil {
+		source.UploadDate = date
+	}
+	return source, true
+}
+
+// Validate checks whether config is complete.
+func (config Config) Validate() error {
+	switch {
+	case config.Name == "":
+		return errors.New("torznab: missing Name")
+	case config.URL == "":
+		return fmt.Errorf("torznab %v: missing URL", config.Name)
+	}
+	return nil
+}
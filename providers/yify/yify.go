@@ -1,14 +1,18 @@
 package yify
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/registry"
 	"github.com/tnychn/torrodle/request"
 	"github.com/tnychn/torrodle/utils"
 )
@@ -16,8 +20,6 @@ import (
 const (
 	Name = "YIFY"
 	Site = "https://yts.am"
-
-	apiURL = "https://yts.am/api"
 )
 
 var trackers = [...]string{
@@ -37,10 +39,19 @@ type provider struct {
 	models.Provider
 }
 
+func init() {
+	registry.MustRegister(New())
+}
+
 func New() models.ProviderInterface {
 	provider := &provider{}
 	provider.Name = Name
 	provider.Site = Site
+	provider.Mirrors = []string{
+		"https://yts.mx",
+		"https://yts.lt",
+		"https://yts.ag",
+	}
 	provider.Categories = models.Categories{
 		All:   "/v2/list_movies.json?query_term=%v&limit=50&page=%d",
 		Movie: "/v2/list_movies.json?query_term=%v&limit=50&page=%d",
@@ -54,6 +65,7 @@ type apiResponse struct {
 	Data          struct {
 		Movies []struct {
 			URL       string `json:"url"`
+			IMDbCode  string `json:"imdb_code"`
 			Title     string `json:"title"`
 			TitleLong string `json:"title_long"`
 			Torrents  []struct {
@@ -64,12 +76,13 @@ type apiResponse struct {
 				Seeds     int    `json:"seeds"`
 				Peers     int    `json:"peers"`
 				SizeBytes int64  `json:"size_bytes"`
+				Uploaded  int64  `json:"date_uploaded_unix"`
 			} `json:"torrents"`
 		} `json:"movies"`
 	} `json:"data"`
 }
 
-func (provider *provider) Search(query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
+func (provider *provider) Search(ctx context.Context, query string, count int, categoryURL models.CategoryURL) ([]models.Source, error) {
 	// categoryURL will be ignored since this provider only searches for movies
 	var results []models.Source
 	if count <= 0 {
@@ -89,13 +102,18 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 
 	// Extract sources
 	logrus.Infoln("YIFY: Getting search results...")
-	_, resp, err := request.Get(nil, apiURL+surl, nil)
-	if err != nil {
-		return results, err
-	}
-
 	response := apiResponse{}
-	if err = json.Unmarshal([]byte(resp), &response); err != nil {
+	err := provider.WithMirrors(ctx, func(site string) error {
+		_, resp, err := request.GetCached(ctx, nil, site+"/api"+surl, nil, request.SearchTTL)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(resp), &response); err != nil {
+			return request.LayoutChanged(site+"/api"+surl, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
 		return results, err
 	}
 
@@ -111,9 +129,12 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 	movies := data.Movies
 	for _, movie := range movies {
 		source := models.Source{
-			From:  provider.Name,
-			Title: movie.TitleLong,
-			URL:   movie.URL,
+			From:     provider.Name,
+			Title:    movie.TitleLong,
+			URL:      movie.URL,
+			Category: "Movies",
+			Uploader: "YIFY",
+			IMDB:     movie.IMDbCode,
 		}
 		torrents := movie.Torrents
 		for _, torrent := range torrents {
@@ -122,6 +143,10 @@ func (provider *provider) Search(query string, count int, categoryURL models.Cat
 			s.Seeders = torrent.Seeds
 			s.Leechers = torrent.Peers
 			s.FileSize = torrent.SizeBytes
+			s.InfoHash = strings.ToLower(torrent.Hash)
+			if torrent.Uploaded > 0 {
+				s.UploadDate = time.Unix(torrent.Uploaded, 0).UTC()
+			}
 			// filter out invalid sources
 			if s.Seeders == 0 {
 				continue
@@ -0,0 +1,132 @@
+// Package registry keeps track of all the available providers.
+// The built-in providers register themselves when their packages are imported,
+// other packages can add their own providers with Register.
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/tnychn/torrodle/models"
+)
+
+var (
+	ErrDuplicate = errors.New("provider already registered")
+	ErrNotFound  = errors.New("provider not registered")
+)
+
+type entry struct {
+	provider models.ProviderInterface
+	enabled  bool
+}
+
+var (
+	mutex   sync.RWMutex
+	entries []*entry // in the order of registration
+)
+
+// Register adds an enabled provider to the registry. The name of the provider must be unique.
+func Register(provider models.ProviderInterface) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if find(provider.GetName()) != nil {
+		return fmt.Errorf("%v: %v", ErrDuplicate, provider.GetName())
+	}
+	entries = append(entries, &entry{provider: provider, enabled: true})
+	return nil
+}
+
+// MustRegister is like Register but panics if the provider cannot be registered.
+// It is meant to be called in the init functions of the providers.
+func MustRegister(provider models.ProviderInterface) {
+	if err := Register(provider); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes a provider from the registry.
+func Unregister(name string) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for i, e := range entries {
+		if e.provider.GetName() == name {
+			entries = append(entries[:i], entries[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%v: %v", ErrNotFound, name)
+}
+
+// Lookup returns the registered provider of the given name, whether it is enabled or not.
+func Lookup(name string) (models.ProviderInterface, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if e := find(name); e != nil {
+		return e.provider, true
+	}
+	return nil, false
+}
+
+// Enable enables a registered provider.
+func Enable(name string) error {
+	return setEnabled(name, true)
+}
+
+// Disable disables a registered provider, so that it is not listed by Enabled.
+func Disable(name string) error {
+	return setEnabled(name, false)
+}
+
+// IsEnabled reports whether the provider of the given name is registered and enabled.
+func IsEnabled(name string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	e := find(name)
+	return e != nil && e.enabled
+}
+
+// All returns all the registered providers in the order of registration.
+func All() []models.ProviderInterface {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	var providers []models.ProviderInterface
+	for _, e := range entries {
+		providers = append(providers, e.provider)
+	}
+	return providers
+}
+
+// Enabled returns all the enabled providers in the order of registration.
+func Enabled() []models.ProviderInterface {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	var providers []models.ProviderInterface
+	for _, e := range entries {
+		if e.enabled {
+			providers = append(providers, e.provider)
+		}
+	}
+	return providers
+}
+
+func setEnabled(name string, enabled bool) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+	e := find(name)
+	if e == nil {
+		return fmt.Errorf("%v: %v", ErrNotFound, name)
+	}
+	e.enabled = enabled
+	return nil
+}
+
+// find returns the entry of the given name, the caller must hold the mutex.
+func find(name string) *entry {
+	for _, e := range entries {
+		if e.provider.GetName() == name {
+			return e
+		}
+	}
+	return nil
+}
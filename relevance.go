@@ -0,0 +1,102 @@
+package torrodle
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/parser"
+	"github.com/tnychn/torrodle/utils"
+)
+
+var tokenRegexp = regexp.MustCompile(`[\pL\pN]+`)
+
+// Relevance scores how relevant a title is to a query, from 0 (unrelated) to 1.
+// The words of the query are matched against the words of the title,
+// and a year or season/episode given in the query must match the ones of the title.
+// IMDb ids in the query are ignored, see relevanceScorer.score for sources whose IMDb id is known.
+func Relevance(query string, title string) float64 {
+	return newRelevanceScorer(query).score(models.Source{Title: title, Release: parser.Parse(title)})
+}
+
+// relevanceScorer scores titles against a query which is parsed only once.
+type relevanceScorer struct {
+	query  parser.Release
+	tokens []string // words of the query without the year, season and episode
+	imdb   string   // IMDb id given in the query
+}
+
+func newRelevanceScorer(query string) relevanceScorer {
+	imdb := utils.FindIMDbID(query)
+	query = utils.RemoveIMDbIDs(query)
+	release := parser.Parse(query)
+	tokens := tokenize(release.Title)
+	if len(tokens) == 0 {
+		tokens = tokenize(query)
+	}
+	return relevanceScorer{query: release, tokens: tokens, imdb: imdb}
+}
+
+// score scores the title of a source whose release is parsed.
+// The words of the query are fully matched if the source has the IMDb id given in the query.
+func (scorer relevanceScorer) score(source models.Source) float64 {
+	title, release := source.Title, source.Release
+	titleTokens := make(map[string]bool)
+	for _, token := range tokenize(title) {
+		titleTokens[token] = true
+	}
+	// a year of the query which is not the year of the release may be part of the title, e.g. "Blade Runner 2049"
+	tokens, year := scorer.tokens, scorer.query.Year
+	if year != 0 && release.Year != year && titleTokens[strconv.Itoa(year)] {
+		tokens = append(append([]string{}, tokens...), strconv.Itoa(year))
+		year = 0
+	}
+
+	var score float64
+	if scorer.imdb != "" && source.IMDB == scorer.imdb {
+		score = 1
+	} else if len(tokens) == 0 {
+		return 0
+	} else {
+		// token overlap
+		matched := 0
+		for _, token := range tokens {
+			if titleTokens[token] {
+				matched++
+			}
+		}
+		score = float64(matched) / float64(len(tokens))
+		// the title is exactly what was searched for, not only containing its words
+		if strings.Join(tokens, " ") != strings.Join(tokenize(release.Title), " ") {
+			score *= 0.9
+		}
+	}
+
+	// year
+	if year != 0 {
+		switch release.Year {
+		case year:
+		case 0:
+			score *= 0.9
+		default:
+			score *= 0.5
+		}
+	}
+	// season and episode
+	if scorer.query.Season != 0 && release.Season != scorer.query.Season {
+		score *= 0.3
+	} else if scorer.query.Episode != 0 && release.Episode != scorer.query.Episode {
+		if release.Episode == 0 {
+			score *= 0.8 // whole season
+		} else {
+			score *= 0.5
+		}
+	}
+	return score
+}
+
+// tokenize splits s into lowercase words.
+func tokenize(s string) []string {
+	return tokenRegexp.FindAllString(strings.ToLower(s), -1)
+}
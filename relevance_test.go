@@ -0,0 +1,30 @@
+package torrodle
+
+import "testing"
+
+func TestRelevance(t *testing.T) {
+	tests := []struct {
+		query string
+		title string
+		min   float64
+		max   float64
+	}{
+		// the number is part of the title, not the year
+		{"blade runner 2049", "Blade.Runner.2049.2017.1080p.BluRay.x264", 0.9, 1},
+		{"wonder woman 1984", "Wonder.Woman.1984.2020.1080p.WEBRip.x264", 0.9, 1},
+		// the year of the query is the year of the release
+		{"blade runner 1982", "Blade.Runner.1982.Final.Cut.1080p.BluRay", 0.9, 1},
+		{"the matrix", "The.Matrix.1999.1080p.BluRay.x264", 0.9, 1},
+		// the year does not match
+		{"dune 2021", "Dune.1984.1080p.BluRay.x264", 0, 0.5},
+		// the season does not match
+		{"breaking bad s02", "Breaking.Bad.S03.1080p.BluRay", 0, 0.3},
+		// unrelated
+		{"the matrix", "Inception.2010.1080p.BluRay", 0, 0},
+	}
+	for _, test := range tests {
+		if score := Relevance(test.query, test.title); score < test.min || score > test.max {
+			t.Errorf("Relevance(%q, %q) = %v, want between %v and %v", test.query, test.title, score, test.min, test.max)
+		}
+	}
+}
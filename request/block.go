@@ -0,0 +1,93 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrBlocked       = errors.New("blocked")                // a challenge, captcha or block page was served instead of the page
+	ErrLayoutChanged = errors.New("unexpected page layout") // the page was served, but the results cannot be found in it
+)
+
+// PageError reports a page which was served but cannot be extracted.
+type PageError struct {
+	Err    error  // ErrBlocked or ErrLayoutChanged
+	URL    string // requested URL
+	Reason string // what was found (or not found) in the page, e.g. "Cloudflare challenge"
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("%v: %v (%v)", e.Err, e.Reason, e.URL)
+}
+
+// Unwrap returns ErrBlocked or ErrLayoutChanged.
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns ErrBlocked or ErrLayoutChanged if err is a *PageError, otherwise err itself.
+func Cause(err error) error {
+	if e, ok := err.(*PageError); ok {
+		return e.Err
+	}
+	return err
+}
+
+// LayoutChanged returns an error wrapping ErrLayoutChanged for the page at url.
+func LayoutChanged(url string, reason string) error {
+	return &PageError{Err: ErrLayoutChanged, URL: url, Reason: reason}
+}
+
+type marker struct {
+	marker string
+	reason string
+}
+
+// markers of the challenge and block pages served instead of the requested ones, matched in lowercase
+var blockMarkers = []marker{
+	{"<title>just a moment...</title>", "Cloudflare challenge"},
+	{"/cdn-cgi/challenge-platform/", "Cloudflare challenge"},
+	{"cf-browser-verification", "Cloudflare challenge"},
+	{"attention required! | cloudflare", "Cloudflare block page"},
+	{"<title>ddos-guard</title>", "DDoS-Guard challenge"},
+	{"access to this site has been blocked", "block page"},
+	{"this website has been blocked", "block page"},
+	{"this site has been blocked", "block page"},
+	{"blocked by order of", "block page"},
+}
+
+// markers of the captchas, only matched in failed responses since pages may embed them in their forms
+var captchaMarkers = []marker{
+	{"g-recaptcha", "captcha"},
+	{"hcaptcha.com", "captcha"},
+}
+
+// maxBlockPageSize is the size above which a successful response is not checked for block pages,
+// since challenges and block pages are small, unlike pages of results which may mention the markers.
+const maxBlockPageSize = 64 * 1024
+
+// detectBlock returns why the page is a challenge or block page, or an empty string if it is not.
+// Successful responses are only checked if they are small HTML pages.
+func detectBlock(code int, contentType string, body string) string {
+	if code == http.StatusUnavailableForLegalReasons {
+		return "legal block"
+	}
+	markers := blockMarkers
+	if code == http.StatusOK {
+		if len(body) > maxBlockPageSize || !strings.Contains(contentType, "html") {
+			return ""
+		}
+	} else {
+		markers = append(captchaMarkers, markers...)
+	}
+	body = strings.ToLower(body)
+	for _, m := range markers {
+		if strings.Contains(body, m.marker) {
+			return m.reason
+		}
+	}
+	return ""
+}
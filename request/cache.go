@@ -0,0 +1,183 @@
+package request
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Time to live of the cached responses passed to GetCached.
+var (
+	SearchTTL = 10 * time.Minute    // for pages of search results, whose seeders change quickly
+	DetailTTL = 30 * 24 * time.Hour // for detail pages of torrents, whose magnet uris never change
+)
+
+// tmpPrefix is the prefix of the files which are being written.
+const tmpPrefix = ".tmp"
+
+var (
+	cacheMutex   sync.Mutex
+	cacheDir     string // empty if the cache is disabled
+	cacheMaxSize int64
+	cacheSize    int64 // total size of the cached responses, -1 if unknown
+)
+
+// DefaultCacheDir returns the directory where the responses are cached by default, under the user cache directory.
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "torrodle", "http")
+}
+
+// SetCache enables the cache of GetCached in dir, whose total size is kept under maxSize bytes by removing the oldest responses.
+// An empty dir disables the cache, which is the default.
+func SetCache(dir string, maxSize int64) error {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	cacheDir = ""
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	// remove the temporary files left by interrupted writes
+	if files, err := ioutil.ReadDir(dir); err == nil {
+		for _, file := range files {
+			if strings.HasPrefix(file.Name(), tmpPrefix) {
+				_ = os.Remove(filepath.Join(dir, file.Name()))
+			}
+		}
+	}
+	cacheDir = dir
+	cacheMaxSize = maxSize
+	cacheSize = -1
+	prune()
+	return nil
+}
+
+// ClearCache removes all the cached responses.
+func ClearCache() error {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	if cacheDir == "" {
+		return nil
+	}
+	files, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		_ = os.Remove(filepath.Join(cacheDir, file.Name()))
+	}
+	cacheSize = 0
+	return nil
+}
+
+// GetCached works like Get, but returns the response cached for url if it is younger than ttl,
+// and caches the response otherwise. The returned client is nil if the response is cached.
+func GetCached(ctx context.Context, client *http.Client, url string, headers map[string]string, ttl time.Duration) (*http.Client, string, error) {
+	cacheMutex.Lock()
+	dir := cacheDir
+	cacheMutex.Unlock()
+	if dir == "" {
+		return Get(ctx, client, url, headers)
+	}
+
+	sum := sha1.Sum([]byte(url))
+	path := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < ttl {
+		if content, err := ioutil.ReadFile(path); err == nil {
+			logrus.Debugf("Cache: hit %v\n", url)
+			return nil, string(content), nil
+		}
+	}
+
+	client, content, err := Get(ctx, client, url, headers)
+	if err != nil || content == "" {
+		return client, content, err
+	}
+	if err := store(dir, path, content); err != nil {
+		logrus.Debugf("Cache: %v\n", err)
+	}
+	return client, content, nil
+}
+
+// store writes the response to path atomically, then prunes the cache if it is too large.
+func store(dir string, path string, content string) error {
+	var replaced int64
+	if info, err := os.Stat(path); err == nil {
+		replaced = info.Size()
+	}
+	tmp, err := ioutil.TempFile(dir, tmpPrefix)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.WriteString(content)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	if cacheSize >= 0 {
+		cacheSize += int64(len(content)) - replaced
+	}
+	if cacheSize < 0 || cacheSize > cacheMaxSize {
+		prune()
+	}
+	return nil
+}
+
+// prune removes the oldest responses until the cache takes at most 90% of its maximum size.
+// cacheMutex must be held.
+func prune() {
+	if cacheDir == "" || cacheMaxSize <= 0 {
+		return
+	}
+	all, err := ioutil.ReadDir(cacheDir)
+	if err != nil {
+		return
+	}
+	var files []os.FileInfo
+	for _, file := range all {
+		if !strings.HasPrefix(file.Name(), tmpPrefix) { // being written
+			files = append(files, file)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	var size int64
+	for _, file := range files {
+		size += file.Size()
+	}
+	for _, file := range files {
+		if size <= cacheMaxSize*9/10 {
+			break
+		}
+		if err := os.Remove(filepath.Join(cacheDir, file.Name())); err == nil {
+			size -= file.Size()
+		}
+	}
+	cacheSize = size
+}
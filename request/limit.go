@@ -0,0 +1,143 @@
+package request
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Limit limits the requests sent to a host, so that scraping many pages at once does not get us banned.
+type Limit struct {
+	Rate     float64 `json:"Rate"`     // requests per second, 0 means unlimited
+	Burst    int     `json:"Burst"`    // requests which can be sent at once before Rate applies
+	InFlight int     `json:"InFlight"` // requests which can be in progress at the same time, 0 means unlimited
+}
+
+// DefaultLimit is the limit of the hosts which have no limit set by SetLimit.
+var DefaultLimit = Limit{Rate: 10, Burst: 10, InFlight: 8}
+
+// limiter is a token bucket refilled at Rate, combined with a semaphore of InFlight slots.
+type limiter struct {
+	limit Limit
+	slots chan struct{}
+
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+var (
+	limitsMutex  sync.Mutex
+	limits       = make(map[string]Limit)    // host -> limit set by SetLimit
+	limiters     = make(map[string]*limiter) // host -> limiter in use
+	defaultLimit = DefaultLimit
+)
+
+// SetLimit sets the limit of the requests sent to host (e.g. "1337x.to").
+func SetLimit(host string, limit Limit) {
+	limitsMutex.Lock()
+	defer limitsMutex.Unlock()
+	limits[host] = limit
+	delete(limiters, host) // requests in progress release the slots of the old limiter
+}
+
+// SetDefaultLimit sets the limit of the hosts which have no limit set by SetLimit.
+func SetDefaultLimit(limit Limit) {
+	limitsMutex.Lock()
+	defer limitsMutex.Unlock()
+	defaultLimit = limit
+	for host := range limiters {
+		if _, ok := limits[host]; !ok {
+			delete(limiters, host)
+		}
+	}
+}
+
+func limiterOf(host string) *limiter {
+	limitsMutex.Lock()
+	defer limitsMutex.Unlock()
+	if l, ok := limiters[host]; ok {
+		return l
+	}
+	limit, ok := limits[host]
+	if !ok {
+		limit = defaultLimit
+	}
+	l := newLimiter(limit)
+	limiters[host] = l
+	return l
+}
+
+func newLimiter(limit Limit) *limiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	l := &limiter{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+	if limit.InFlight > 0 {
+		l.slots = make(chan struct{}, limit.InFlight)
+	}
+	return l
+}
+
+// acquire waits for a free slot and a token, the returned function releases the slot.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		once := sync.Once{}
+		release = func() { once.Do(func() { <-l.slots }) }
+	}
+	if err := l.wait(ctx); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// wait takes a token from the bucket, waiting until one is available.
+func (l *limiter) wait(ctx context.Context) error {
+	if l.limit.Rate <= 0 {
+		return nil
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.limit.Rate
+	if max := float64(l.limit.Burst); l.tokens > max {
+		l.tokens = max
+	}
+	l.last = now
+	// reserve the token, the bucket goes negative while requests are waiting
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.limit.Rate * float64(time.Second))
+	l.mutex.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-ctx.Done():
+		l.mutex.Lock()
+		l.tokens++ // give the reservation back
+		l.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// releaser releases the slot of a request once its body is closed.
+type releaser struct {
+	io.ReadCloser
+	release func()
+}
+
+func (r releaser) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
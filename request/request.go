@@ -1,21 +1,86 @@
 package request
 
 import (
-	"errors"
+	"context"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 const agent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_10_5) AppleWebKit/603.3.8 (KHTML, like Gecko) Version/10.1.2 Safari/603.3.8"
 
+const snippetSize = 512
+
+// StatusError is returned by Get if the status of the response is not 200 OK.
+type StatusError struct {
+	Code       int           // status code, e.g. 503
+	Status     string        // status line, e.g. "503 Service Unavailable"
+	URL        string        // requested URL
+	Body       string        // beginning of the body of the response
+	RetryAfter time.Duration // delay asked by the Retry-After header, zero if none
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%v (%v)", e.Status, e.URL)
+}
+
+// Temporary reports whether the request may succeed if it is retried later,
+// i.e. the server is overloaded (5xx) or rate limits the requests (429).
+func (e *StatusError) Temporary() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code == http.StatusRequestTimeout || e.Code >= 500
+}
+
+// RetryPolicy tells Get how to retry the requests which failed temporarily.
+type RetryPolicy struct {
+	MaxAttempts int           // attempts of a request including the first one, 1 disables retrying
+	MinBackoff  time.Duration // delay before the first retry, doubled for each of the next ones
+	MaxBackoff  time.Duration // maximum delay before a retry, a longer Retry-After is not waited for
+	Jitter      float64       // fraction of the delay which is randomly added or removed, from 0 to 1
+}
+
+// DefaultRetryPolicy is the retry policy used unless SetRetryPolicy is called.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  10 * time.Second,
+	Jitter:      0.2,
+}
+
+var (
+	policyMutex sync.RWMutex
+	policy      = DefaultRetryPolicy
+)
+
+// SetRetryPolicy sets the retry policy of all the following requests.
+func SetRetryPolicy(p RetryPolicy) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+	policy = p
+}
+
+// GetRetryPolicy returns the current retry policy.
+func GetRetryPolicy() RetryPolicy {
+	policyMutex.RLock()
+	defer policyMutex.RUnlock()
+	return policy
+}
+
 // Request is a base function for sending HTTP requests.
-func Request(client *http.Client, method string, url string, header http.Header) (*http.Client, *http.Response, http.Header, error) {
+// The request is cancelled as soon as ctx is done.
+// It waits as long as the limit of the host requires (see SetLimit), the body of the response must be closed to free its slot.
+func Request(ctx context.Context, client *http.Client, method string, url string, header http.Header) (*http.Client, *http.Response, http.Header, error) {
 	if client == nil {
 		// Make a new http client with cookie jar if no existing client is provided
 		jar, _ := cookiejar.New(nil)
-		client = &http.Client{Timeout: 30 * time.Second, Jar: jar}
+		client = &http.Client{Timeout: 30 * time.Second, Jar: jar, Transport: transport}
 	}
 	// Build a new request
 	req, err := http.NewRequest(method, url, nil)
@@ -23,6 +88,7 @@ func Request(client *http.Client, method string, url string, header http.Header)
 		// logrus.Errorln(err)
 		return nil, nil, nil, err
 	}
+	req = req.WithContext(ctx)
 
 	// Set headers
 	if header != nil {
@@ -32,30 +98,133 @@ func Request(client *http.Client, method string, url string, header http.Header)
 		req.Header = header
 	}
 
+	// Wait for the limit of the host
+	release, err := limiterOf(req.URL.Host).acquire(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// Do request
 	// logrus.Debugf("Sending %v request to %v with headers %v\n", req.Method, req.URL, req.Header)
 	res, err := client.Do(req)
 	if err != nil {
 		// logrus.Errorln(err)
+		release()
 		return nil, nil, nil, err
 	}
+	res.Body = releaser{ReadCloser: res.Body, release: release}
 	return client, res, req.Header, nil
 }
 
 // Get wraps the Request function, sends a HTTP GET request, returns the smae client and the html of the content body.
-func Get(client *http.Client, url string, headers map[string]string) (*http.Client, string, error) {
+// Requests which failed temporarily are retried according to the retry policy (see SetRetryPolicy).
+// A *PageError wrapping ErrBlocked is returned if a challenge or block page is served instead,
+// otherwise a *StatusError is returned if the status of the response is not 200 OK.
+func Get(ctx context.Context, client *http.Client, url string, headers map[string]string) (*http.Client, string, error) {
+	p := GetRetryPolicy()
+	for attempt := 1; ; attempt++ {
+		c, content, err := get(ctx, client, url, headers)
+		if err == nil {
+			return c, content, nil
+		}
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return nil, "", err
+		}
+		delay := p.backoff(attempt)
+		if e, ok := err.(*StatusError); ok && e.RetryAfter > 0 {
+			if e.RetryAfter > p.MaxBackoff {
+				return nil, "", err
+			}
+			delay = e.RetryAfter
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, "", err
+		}
+	}
+}
+
+func get(ctx context.Context, client *http.Client, url string, headers map[string]string) (*http.Client, string, error) {
 	header := http.Header{}
 	for k, v := range headers {
 		header.Set(k, v)
 	}
-	client, res, _, err := Request(client, "GET", url, header)
+	client, res, _, err := Request(ctx, client, "GET", url, header)
 	if err != nil {
 		return nil, "", err
 	}
 	defer res.Body.Close()
 	if res.StatusCode != 200 {
-		return nil, "", errors.New(res.Status)
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxBlockPageSize))
+		if reason := detectBlock(res.StatusCode, res.Header.Get("Content-Type"), string(body)); reason != "" {
+			return nil, "", &PageError{Err: ErrBlocked, URL: url, Reason: reason}
+		}
+		if len(body) > snippetSize {
+			body = body[:snippetSize]
+		}
+		return nil, "", &StatusError{
+			Code:       res.StatusCode,
+			Status:     res.Status,
+			URL:        url,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 	content, err := ioutil.ReadAll(res.Body)
-	return client, string(content), err
+	if err != nil {
+		return nil, "", err
+	}
+	if reason := detectBlock(res.StatusCode, res.Header.Get("Content-Type"), string(content)); reason != "" {
+		return nil, "", &PageError{Err: ErrBlocked, URL: url, Reason: reason}
+	}
+	return client, string(content), nil
+}
+
+// backoff returns the delay before the retry after the given attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MinBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(float64(delay) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	return delay
+}
+
+// retryable reports whether a request which failed with err may succeed if it is retried.
+// Timeouts are not retried, since a site which does not answer would stall the failover to its mirrors.
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case *StatusError:
+		return e.Temporary()
+	case net.Error:
+		if e.Timeout() {
+			return false
+		}
+	}
+	if err == io.ErrUnexpectedEOF {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") || strings.HasSuffix(msg, "EOF")
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either seconds or a HTTP date.
+// Returns zero if it cannot be parsed.
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if s == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(s)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(s); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
 }
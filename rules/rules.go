@@ -0,0 +1,156 @@
+// Package rules scores sources with user-defined rules, e.g. to prefer some release groups or ban CAM releases.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/tnychn/torrodle/models"
+)
+
+// Rule adjusts the score of the sources which match all of its conditions.
+// Empty conditions match any source.
+type Rule struct {
+	Name        string   `json:"Name"`        // describes the rule in error messages
+	Pattern     string   `json:"Pattern"`     // regular expression matched against the title (case-insensitive)
+	Resolutions []string `json:"Resolutions"` // resolutions of the parsed release (e.g. "2160p")
+	Sources     []string `json:"Sources"`     // sources of the parsed release (e.g. "BluRay", "CAM")
+	Codecs      []string `json:"Codecs"`      // codecs of the parsed release (e.g. "x265")
+	Groups      []string `json:"Groups"`      // release groups of the parsed release
+	Providers   []string `json:"Providers"`   // names of the providers which listed the source
+	Categories  []string `json:"Categories"`  // keywords of the category given by the provider (e.g. "TV")
+	MinSize     string   `json:"MinSize"`     // minimum file size (e.g. "700MB")
+	MaxSize     string   `json:"MaxSize"`     // maximum file size (e.g. "4GB")
+	Score       int      `json:"Score"`       // added to the score of a matching source (negative to subtract)
+	Ban         bool     `json:"Ban"`         // drop the matching sources altogether
+}
+
+// compiledRule is a Rule whose pattern and sizes are parsed.
+type compiledRule struct {
+	Rule
+	re      *regexp.Regexp
+	minSize int64
+	maxSize int64
+}
+
+// Engine scores sources with a list of rules.
+type Engine struct {
+	rules []compiledRule
+}
+
+// New compiles the rules into an Engine.
+func New(rules []Rule) (*Engine, error) {
+	engine := &Engine{}
+	for i, rule := range rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		compiled := compiledRule{Rule: rule}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v: %v", name, err)
+			}
+			compiled.re = re
+		}
+		if rule.MinSize != "" {
+			size, err := humanize.ParseBytes(rule.MinSize)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v: %v", name, err)
+			}
+			compiled.minSize = int64(size)
+		}
+		if rule.MaxSize != "" {
+			size, err := humanize.ParseBytes(rule.MaxSize)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v: %v", name, err)
+			}
+			compiled.maxSize = int64(size)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// Score returns the sum of the scores of all the rules which match the source,
+// and whether any of them bans the source.
+func (engine *Engine) Score(source models.Source) (score int, banned bool) {
+	for _, rule := range engine.rules {
+		if !rule.match(source) {
+			continue
+		}
+		score += rule.Score
+		if rule.Ban {
+			banned = true
+		}
+	}
+	return score, banned
+}
+
+// Apply sets the Score of every source and drops the banned ones.
+func (engine *Engine) Apply(sources []models.Source) []models.Source {
+	var scored []models.Source
+	for _, source := range sources {
+		score, banned := engine.Score(source)
+		if banned {
+			continue
+		}
+		source.Score = score
+		scored = append(scored, source)
+	}
+	return scored
+}
+
+func (rule compiledRule) match(source models.Source) bool {
+	providers := source.Providers
+	if len(providers) == 0 {
+		providers = []string{source.From}
+	}
+	release := source.Release
+	switch {
+	case rule.re != nil && !rule.re.MatchString(source.Title):
+	case len(rule.Resolutions) > 0 && !containsFold(rule.Resolutions, release.Resolution):
+	case len(rule.Sources) > 0 && !containsFold(rule.Sources, release.Source):
+	case len(rule.Codecs) > 0 && !containsFold(rule.Codecs, release.Codec):
+	case len(rule.Groups) > 0 && !containsFold(rule.Groups, release.Group):
+	case len(rule.Providers) > 0 && !anyFold(rule.Providers, providers):
+	case len(rule.Categories) > 0 && !containsKeyword(source.Category, rule.Categories):
+	case rule.minSize > 0 && source.FileSize < rule.minSize:
+	case rule.maxSize > 0 && source.FileSize > rule.maxSize:
+	default:
+		return true
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyFold(list []string, items []string) bool {
+	for _, item := range items {
+		if containsFold(list, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsKeyword(s string, keywords []string) bool {
+	s = strings.ToLower(s)
+	for _, keyword := range keywords {
+		if strings.Contains(s, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
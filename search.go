@@ -0,0 +1,234 @@
+package torrodle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/parser"
+	"github.com/tnychn/torrodle/rules"
+)
+
+// SearchOptions specifies what to search for and which providers to query.
+type SearchOptions struct {
+	Providers []models.ProviderInterface // providers to query concurrently
+	Query     string                     // search query
+	Count     int                        // maximum amount of results (at most 500)
+	Category  Category                   // category to search in
+	SortBy    SortBy                     // how the results are sorted (SortByDefault if empty)
+	Sort      SortSpec                   // how the results are sorted by multiple keys (overrides SortBy)
+	Timeout   time.Duration              // timeout of each provider (no timeout if zero)
+	Filter    Filter                     // criteria which the results must satisfy
+	Rules     *rules.Engine              // user-defined rules which score the results (optional)
+	Progress  func(SearchEvent)          // called by Search for every event of the providers (optional)
+}
+
+// sortSpec returns Sort, or SortBy in descending order if Sort is empty.
+// An empty SortBy sorts by SortByDefault.
+func (opts SearchOptions) sortSpec() SortSpec {
+	if len(opts.Sort) > 0 {
+		return opts.Sort
+	}
+	if opts.SortBy == "" {
+		return SortSpec{{By: SortByDefault, Desc: true}}
+	}
+	return SortSpec{{By: opts.SortBy, Desc: true}}
+}
+
+// EventType is the type of a SearchEvent.
+type EventType int
+
+const (
+	EventStarted EventType = iota // the provider started searching
+	EventResult                   // the provider returned a batch of sources
+	EventFailed                   // the provider failed, timed out or found nothing
+	EventDone                     // the provider finished, no more events of it will follow
+)
+
+// SearchEvent reports the progress of a single provider during SearchStream.
+type SearchEvent struct {
+	Type     EventType
+	Provider string          // name of the provider
+	Sources  []models.Source // batch of sources (EventResult only)
+	Err      *ProviderError  // error of the provider (EventFailed only)
+}
+
+// SearchStream queries all the providers in opts concurrently and emits their progress as events.
+// Each provider emits EventStarted, then EventResult or EventFailed, and finally EventDone.
+// The channel is closed after every provider is done. Providers which have not answered before
+// ctx is done or before their own timeout fail with ErrTimeout (or ctx.Err()).
+// Sources in each batch are sorted by opts.Sort (or opts.SortBy), the batches themselves arrive as the providers answer.
+// A provider listed more than once (by name) is only searched once.
+func SearchStream(ctx context.Context, opts SearchOptions) (<-chan SearchEvent, error) {
+	// Validate arguments before querying any provider
+	if _, err := GetCategoryURL(opts.Category, models.Categories{}); err != nil {
+		return nil, err
+	}
+	if _, err := SortResults(nil, opts.sortSpec()); err != nil {
+		return nil, err
+	}
+	if _, err := FilterResults(nil, opts.Filter); err != nil {
+		return nil, err
+	}
+	if opts.Count > 500 {
+		logrus.Warningln("'count' should not be larger than 500, set to 500 automatically")
+		opts.Count = 500
+	}
+
+	// Search each provider once, since the events and batches are keyed by its name
+	var providers []models.ProviderInterface
+	names := make(map[string]bool)
+	for _, provider := range opts.Providers {
+		if !names[provider.GetName()] {
+			names[provider.GetName()] = true
+			providers = append(providers, provider)
+		}
+	}
+
+	// buffered so that providers never block on a slow consumer
+	events := make(chan SearchEvent, 3*len(providers))
+	wg := sync.WaitGroup{}
+	for _, provider := range providers {
+		wg.Add(1)
+		go func(provider models.ProviderInterface) {
+			defer wg.Done()
+			name := provider.GetName()
+			events <- SearchEvent{Type: EventStarted, Provider: name}
+			sources, err := searchProvider(ctx, provider, opts)
+			if err != nil {
+				events <- SearchEvent{Type: EventFailed, Provider: name, Err: err.(*ProviderError)}
+			} else {
+				events <- SearchEvent{Type: EventResult, Provider: name, Sources: sources}
+			}
+			events <- SearchEvent{Type: EventDone, Provider: name}
+		}(provider)
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+	return events, nil
+}
+
+// Search queries all the providers in opts concurrently and returns the sorted results.
+// Results of different providers referring to the same torrent are merged (see MergeResults).
+// Providers which have not answered before ctx is done or before their own timeout are given up,
+// and the results collected so far are returned along with ProviderErrors.
+func Search(ctx context.Context, opts SearchOptions) ([]models.Source, error) {
+	events, err := SearchStream(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect the batches in the order of the providers
+	batches := make(map[string][]models.Source)
+	var errs ProviderErrors
+	for event := range events {
+		if opts.Progress != nil {
+			opts.Progress(event)
+		}
+		switch event.Type {
+		case EventResult:
+			batches[event.Provider] = append(batches[event.Provider], event.Sources...)
+		case EventFailed:
+			errs = append(errs, event.Err)
+		}
+	}
+	var results []models.Source
+	for _, provider := range opts.Providers {
+		results = append(results, batches[provider.GetName()]...)
+		delete(batches, provider.GetName()) // a provider listed twice is only searched (and collected) once
+	}
+	results = MergeResults(results)
+	logrus.Infof("Returning %d results in total...\n", len(results))
+
+	results, _ = SortResults(results, opts.sortSpec())
+	count := opts.Count
+	if count > 500 {
+		count = 500
+	}
+	if count > len(results) {
+		count = len(results)
+	}
+	if len(errs) > 0 {
+		return results[:count], errs
+	}
+	return results[:count], nil
+}
+
+// searchProvider searches a single provider and gives up as soon as ctx is done or opts.Timeout is reached.
+// The results are filtered, scored, merged, sorted and truncated according to opts, which must have been validated already.
+// The returned error is always a *ProviderError.
+func searchProvider(ctx context.Context, provider models.ProviderInterface, opts SearchOptions) ([]models.Source, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	caturl, _ := GetCategoryURL(opts.Category, provider.GetCategories())
+	if caturl == "" {
+		logrus.Warningf("'%v' provider does not support category '%v', getting default category (ALL)...", provider.GetName(), opts.Category)
+	}
+
+	type response struct {
+		sources []models.Source
+		err     error
+	}
+	done := make(chan response, 1)
+	go func() {
+		sources, err := provider.Search(ctx, opts.Query, opts.Count, caturl)
+		done <- response{sources, err}
+	}()
+
+	var sources []models.Source
+	select {
+	case res := <-done:
+		if res.err != nil {
+			if ctx.Err() != nil {
+				res.err = contextError(ctx.Err())
+			}
+			return nil, &ProviderError{Provider: provider.GetName(), Err: res.err}
+		}
+		sources = res.sources
+	case <-ctx.Done():
+		return nil, &ProviderError{Provider: provider.GetName(), Err: contextError(ctx.Err())}
+	}
+
+	if len(sources) == 0 {
+		logrus.Warningf("No torrents found via '%v'\n", provider.GetName())
+		return sources, &ProviderError{Provider: provider.GetName(), Err: ErrNoResults}
+	}
+	scorer := newRelevanceScorer(opts.Query)
+	for i := range sources {
+		if sources[i].Release.Title == "" {
+			sources[i].Release = parser.Parse(sources[i].Title)
+		}
+		sources[i].Relevance = scorer.score(sources[i])
+	}
+	sources, _ = FilterResults(sources, opts.Filter)
+	if opts.Rules != nil {
+		sources = opts.Rules.Apply(sources)
+	}
+	if len(sources) == 0 {
+		logrus.Warningf("No torrents left via '%v' after filtering\n", provider.GetName())
+		return sources, &ProviderError{Provider: provider.GetName(), Err: ErrNoResults}
+	}
+	results, _ := SortResults(MergeResults(sources), opts.sortSpec())
+	count := opts.Count
+	if count > len(results) {
+		count = len(results)
+	}
+	return results[:count], nil
+}
+
+// contextError converts context.DeadlineExceeded into ErrTimeout.
+func contextError(err error) error {
+	if err == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
@@ -0,0 +1,140 @@
+package torrodle
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tnychn/torrodle/models"
+)
+
+// SortKey is a single key of a SortSpec.
+type SortKey struct {
+	By   SortBy
+	Desc bool
+}
+
+// SortSpec is an ordered list of sort keys, each key breaks the ties of the keys before it.
+type SortSpec []SortKey
+
+func (spec SortSpec) String() string {
+	var keys []string
+	for _, key := range spec {
+		order := "asc"
+		if key.Desc {
+			order = "desc"
+		}
+		keys = append(keys, string(key.By)+" "+order)
+	}
+	return strings.Join(keys, ", ")
+}
+
+// ParseSortSpec parses a comma-separated list of sort keys such as "seeders desc, size asc".
+// The order of a key is descending if it is omitted.
+func ParseSortSpec(s string) (SortSpec, error) {
+	var spec SortSpec
+	for _, field := range strings.Split(s, ",") {
+		words := strings.Fields(strings.ToLower(field))
+		if len(words) == 0 {
+			continue
+		}
+		key := SortKey{By: SortBy(words[0]), Desc: true}
+		if _, ok := sortKeys[key.By]; !ok {
+			return nil, fmt.Errorf("%v: %v", ErrInvalidSortBy, words[0])
+		}
+		if len(words) > 2 {
+			return nil, fmt.Errorf("%v: %v", ErrInvalidSortBy, strings.TrimSpace(field))
+		}
+		if len(words) == 2 {
+			switch words[1] {
+			case "asc":
+				key.Desc = false
+			case "desc":
+			default:
+				return nil, fmt.Errorf("%v: invalid order %v", ErrInvalidSortBy, words[1])
+			}
+		}
+		spec = append(spec, key)
+	}
+	return spec, nil
+}
+
+// compareFunc returns a negative number if a < b, a positive number if a > b, or zero if they are equal.
+type compareFunc func(a, b *models.Source) int
+
+var sortKeys = map[SortBy]compareFunc{
+	SortByDefault: func(a, b *models.Source) int {
+		return 0
+	},
+	SortBySeeders: func(a, b *models.Source) int {
+		return a.Seeders - b.Seeders
+	},
+	SortByLeechers: func(a, b *models.Source) int {
+		return a.Leechers - b.Leechers
+	},
+	SortBySize: func(a, b *models.Source) int {
+		return compareFloat(float64(a.FileSize), float64(b.FileSize))
+	},
+	SortByRatio: func(a, b *models.Source) int {
+		return compareFloat(ratio(a), ratio(b))
+	},
+	SortByDate: func(a, b *models.Source) int {
+		switch {
+		case a.UploadDate.Before(b.UploadDate):
+			return -1
+		case a.UploadDate.After(b.UploadDate):
+			return 1
+		}
+		return 0
+	},
+	SortByResolution: func(a, b *models.Source) int {
+		return a.Release.Height() - b.Release.Height()
+	},
+	SortByRelevance: func(a, b *models.Source) int {
+		return compareFloat(a.Relevance, b.Relevance)
+	},
+	SortByScore: func(a, b *models.Source) int {
+		return a.Score - b.Score
+	},
+}
+
+// SortResults sorts the results in place according to spec.
+// The sort is stable, results which are equal on every key keep their order.
+func SortResults(results []models.Source, spec SortSpec) ([]models.Source, error) {
+	compares := make([]compareFunc, len(spec))
+	for i, key := range spec {
+		compare, ok := sortKeys[key.By]
+		if !ok {
+			return results, ErrInvalidSortBy
+		}
+		if key.Desc {
+			compares[i] = func(a, b *models.Source) int { return compare(b, a) }
+		} else {
+			compares[i] = compare
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, compare := range compares {
+			if c := compare(&results[i], &results[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return results, nil
+}
+
+// ratio returns the seeders to leechers ratio of a source.
+func ratio(source *models.Source) float64 {
+	return float64(source.Seeders) / float64(source.Leechers+1)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -1,21 +1,19 @@
 package torrodle
 
 import (
-	"sort"
-	"time"
-
-	"github.com/briandowns/spinner"
-	"github.com/fatih/color"
-	"github.com/sirupsen/logrus"
+	"context"
 
 	"github.com/tnychn/torrodle/models"
+	"github.com/tnychn/torrodle/providers/eztv"
 	"github.com/tnychn/torrodle/providers/leetx"
 	"github.com/tnychn/torrodle/providers/limetorrents"
+	"github.com/tnychn/torrodle/providers/nyaa"
 	"github.com/tnychn/torrodle/providers/rarbg"
 	"github.com/tnychn/torrodle/providers/sukebei"
 	"github.com/tnychn/torrodle/providers/thepiratebay"
 	"github.com/tnychn/torrodle/providers/torrentz"
 	"github.com/tnychn/torrodle/providers/yify"
+	"github.com/tnychn/torrodle/registry"
 )
 
 type Category string
@@ -32,109 +30,98 @@ const (
 	SortBySeeders  SortBy = "seeders"
 	SortByLeechers SortBy = "leechers"
 	SortBySize     SortBy = "size"
+
+	SortByRatio      SortBy = "ratio"      // seeders to leechers ratio
+	SortByDate       SortBy = "date"       // upload date
+	SortByResolution SortBy = "resolution" // resolution of the parsed release
+	SortByRelevance  SortBy = "relevance"  // relevance of the title to the query
+	SortByScore      SortBy = "score"      // score given by the user-defined rules
 )
 
-// Expose all the providers
+// Expose all the built-in providers, which are registered in the registry as well
 var (
-	SukebeiProvider      = sukebei.New()
-	ThePirateBayProvider = thepiratebay.New()
-	LimeTorrentsProvider = limetorrents.New()
-	Torrentz2Provider    = torrentz.New()
-	RarbgProvider        = rarbg.New()
-	LeetxProvider        = leetx.New()
-	YifyProvider         = yify.New()
+	NyaaProvider         = mustLookup(nyaa.Name)
+	SukebeiProvider      = mustLookup(sukebei.Name)
+	ThePirateBayProvider = mustLookup(thepiratebay.Name)
+	LimeTorrentsProvider = mustLookup(limetorrents.Name)
+	Torrentz2Provider    = mustLookup(torrentz.Name)
+	RarbgProvider        = mustLookup(rarbg.Name)
+	LeetxProvider        = mustLookup(leetx.Name)
+	YifyProvider         = mustLookup(yify.Name)
+	EZTVProvider         = mustLookup(eztv.Name)
 )
 
-var AllProviders = [...]models.ProviderInterface{
-	SukebeiProvider,
-	ThePirateBayProvider,
-	LimeTorrentsProvider,
-	Torrentz2Provider,
-	RarbgProvider,
-	LeetxProvider,
-	YifyProvider,
+func mustLookup(name string) models.ProviderInterface {
+	provider, ok := registry.Lookup(name)
+	if !ok {
+		panic("torrodle: provider not registered: " + name)
+	}
+	return provider
 }
 
 // ListProviderResults lists all results queried from this specific provider only.
 // It sorts the results and returns at most {count} results.
-func ListProviderResults(provider models.ProviderInterface, query string, count int, category Category, sortBy SortBy) []models.Source {
-	var sources []models.Source
-	categories := provider.GetCategories()
-	caturl := GetCategoryURL(category, categories)
-	if caturl == "" {
-		logrus.Warningf("'%v' provider does not support category '%v', getting default category (ALL)...", provider.GetName(), category)
-	}
-	sources, err := provider.Search(query, count, caturl)
-	if err != nil {
-		logrus.Fatalln(err)
-	}
-	if len(sources) == 0 {
-		logrus.Warningf("No torrents found via '%v'\n", provider.GetName())
+// A *ProviderError is returned if the provider failed or found nothing.
+func ListProviderResults(provider models.ProviderInterface, query string, count int, category Category, sortBy SortBy) ([]models.Source, error) {
+	if _, err := GetCategoryURL(category, models.Categories{}); err != nil {
+		return nil, err
 	}
-	results := GetSortedResults(sources, sortBy)
-	if count > len(results) {
-		count = len(results)
+	if _, err := GetSortedResults(nil, sortBy); err != nil {
+		return nil, err
 	}
-	return results[:count]
+	return searchProvider(context.Background(), provider, SearchOptions{
+		Query:    query,
+		Count:    count,
+		Category: category,
+		SortBy:   sortBy,
+	})
 }
 
 // ListResults lists all results queried from all the specified providers.
+// Providers can be given by their names in the registry as well.
 // It sorts the results after collected all the sorted results from different providers.
 // Returns at most {count} results.
-func ListResults(providers []interface{}, query string, count int, category Category, sortBy SortBy) []models.Source {
+// If some of the providers failed, the results of the others are returned along with ProviderErrors.
+func ListResults(providers []interface{}, query string, count int, category Category, sortBy SortBy) ([]models.Source, error) {
+	var errs ProviderErrors
 	var argProviders []models.ProviderInterface
 	for _, p := range providers {
 		switch p.(type) {
 		case string:
-			for _, p2 := range AllProviders {
-				if p2.GetName() == p.(string) {
-					argProviders = append(argProviders, p2)
-				}
+			if provider, ok := registry.Lookup(p.(string)); ok {
+				argProviders = append(argProviders, provider)
+			} else {
+				errs = append(errs, &ProviderError{Provider: p.(string), Err: ErrUnknownProvider})
 			}
 		case models.ProviderInterface:
 			argProviders = append(argProviders, p.(models.ProviderInterface))
 		default:
-			logrus.Fatalln("Invalid interface type in 'providers': only 'string' and 'models.ProviderInterface' are accepted")
+			return nil, ErrInvalidProvider
 		}
 	}
 
-	// Init spinner
-	var s *spinner.Spinner
-	showSpinner := logrus.GetLevel() <= logrus.WarnLevel
-
-	if count > 500 {
-		logrus.Warningln("'count' should not be larger than 500, set to 500 automatically")
-		count = 500
-	}
-
-	// Get results from providers
-	var results []models.Source
-	for _, provider := range argProviders {
-		if showSpinner {
-			c := color.New(color.FgYellow, color.Bold)
-			s = spinner.New(spinner.CharSets[33], 100*time.Millisecond)
-			_ = s.Color("fgBlue")
-			s.Suffix = c.Sprint(" Waiting for ") + color.GreenString(provider.GetName()) + c.Sprint(" ...")
-			s.Start()
-		}
-
-		sources := ListProviderResults(provider, query, count, category, sortBy)
-		results = append(results, sources...)
-		if showSpinner && s != nil {
-			s.Stop()
+	results, err := Search(context.Background(), SearchOptions{
+		Providers: argProviders,
+		Query:     query,
+		Count:     count,
+		Category:  category,
+		SortBy:    sortBy,
+	})
+	if err != nil {
+		perrs, ok := err.(ProviderErrors)
+		if !ok {
+			return nil, err
 		}
+		errs = append(errs, perrs...)
 	}
-	logrus.Infof("Returning %d results in total...\n", len(results))
-
-	results = GetSortedResults(results, sortBy)
-	if count > len(results) {
-		count = len(results)
+	if len(errs) > 0 {
+		return results, errs
 	}
-	return results[:count]
+	return results, nil
 }
 
 // GetCategoryURL returns CategoryURL according to the category name (constant).
-func GetCategoryURL(category Category, categories models.Categories) models.CategoryURL {
+func GetCategoryURL(category Category, categories models.Categories) (models.CategoryURL, error) {
 	var caturl models.CategoryURL
 	switch category {
 	case CategoryAll:
@@ -148,30 +135,13 @@ func GetCategoryURL(category Category, categories models.Categories) models.Cate
 	case CategoryPorn:
 		caturl = categories.Porn
 	default:
-		logrus.Fatalln("Invalid category")
+		return "", ErrInvalidCategory
 	}
-	return caturl
+	return caturl, nil
 }
 
-func GetSortedResults(results []models.Source, sortBy SortBy) []models.Source {
-	// Sort results
-	switch sortBy {
-	case SortByDefault:
-		// nothing to do
-	case SortBySeeders:
-		sort.Slice(results, func(i, j int) bool {
-			return results[i].Seeders > results[j].Seeders
-		})
-	case SortByLeechers:
-		sort.Slice(results, func(i, j int) bool {
-			return results[i].Leechers > results[j].Leechers
-		})
-	case SortBySize:
-		sort.Slice(results, func(i, j int) bool {
-			return results[i].FileSize > results[j].FileSize
-		})
-	default:
-		logrus.Fatalln("Invalid SortBy")
-	}
-	return results
+// GetSortedResults sorts the results in place according to sortBy (constant) in descending order.
+// See SortResults for sorting by multiple keys.
+func GetSortedResults(results []models.Source, sortBy SortBy) ([]models.Source, error) {
+	return SortResults(results, SortSpec{{By: sortBy, Desc: true}})
 }
@@ -1,6 +1,21 @@
 package utils
 
-import "math"
+import (
+	"encoding/base32"
+	"encoding/hex"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	btihRegexp = regexp.MustCompile(`(?i)urn:btih:([a-z0-9]+)`)
+	agoRegexp  = regexp.MustCompile(`(?i)(\d+|an?)\s*(sec|min|hour|hr|day|week|month|year|yr)`)
+	imdbRegexp = regexp.MustCompile(`(?i)\btt(\d{7,8})\b`)
+)
 
 // ComputePageCount computes pages needed to paginate in order to get the count of items.
 func ComputePageCount(count int, countPerPage int) int {
@@ -10,3 +25,90 @@ func ComputePageCount(count int, countPerPage int) int {
 	}
 	return pages
 }
+
+// InfoHashFromMagnet extracts the info hash (btih) of a magnet uri as a lowercase hex string.
+// Base32 encoded hashes are converted to hex. Returns an empty string if no valid hash is found.
+func InfoHashFromMagnet(magnet string) string {
+	match := btihRegexp.FindStringSubmatch(magnet)
+	if match == nil {
+		return ""
+	}
+	hash := match[1]
+	switch len(hash) {
+	case 40:
+		if _, err := hex.DecodeString(hash); err != nil {
+			return ""
+		}
+		return strings.ToLower(hash)
+	case 32:
+		b, err := base32.StdEncoding.DecodeString(strings.ToUpper(hash))
+		if err != nil {
+			return ""
+		}
+		return hex.EncodeToString(b)
+	}
+	return ""
+}
+
+// ParseTimeAgo converts a relative time such as "5 mins ago" or "2 years" into an absolute time before now.
+// Returns the zero time if it cannot be parsed.
+func ParseTimeAgo(s string, now time.Time) time.Time {
+	match := agoRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return time.Time{}
+	}
+	n, err := strconv.Atoi(match[1])
+	if err != nil {
+		n = 1 // "a" or "an"
+	}
+	switch strings.ToLower(match[2]) {
+	case "sec":
+		return now.Add(-time.Duration(n) * time.Second)
+	case "min":
+		return now.Add(-time.Duration(n) * time.Minute)
+	case "hour", "hr":
+		return now.Add(-time.Duration(n) * time.Hour)
+	case "day":
+		return now.AddDate(0, 0, -n)
+	case "week":
+		return now.AddDate(0, 0, -7*n)
+	case "month":
+		return now.AddDate(0, -n, 0)
+	default: // year
+		return now.AddDate(-n, 0, 0)
+	}
+}
+
+// FindIMDbID returns the first IMDb id (e.g. "tt0944947") found in s, or an empty string if there is none.
+func FindIMDbID(s string) string {
+	match := imdbRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+	return "tt" + match[1]
+}
+
+// RemoveIMDbIDs removes all the IMDb ids from s.
+func RemoveIMDbIDs(s string) string {
+	return strings.TrimSpace(imdbRegexp.ReplaceAllString(s, ""))
+}
+
+// ContainsWords reports whether s contains every word of the query, ignoring case.
+func ContainsWords(s string, query string) bool {
+	s = strings.ToLower(s)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(s, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// SiteOf returns the scheme and host of a URL, e.g. "https://1337x.to" of "https://1337x.to/search/x/1/".
+func SiteOf(surl string) string {
+	u, err := url.Parse(surl)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}